@@ -0,0 +1,370 @@
+// Package ast provides typed syntax trees for Lojban text.
+//
+// The parse trees returned by parser.Parse encode the meaning of each node
+// only in its rule name, and the rule names differ between dialects.
+// Convert builds a tree of the types in this package from such a parse tree,
+// so that callers can inspect sentences, sumti, and selbri
+// without matching on grammar rule names.
+package ast
+
+import "github.com/eaburns/peggy/peg"
+
+// A Text is a whole Lojban text.
+type Text struct {
+	// Paragraphs are the paragraphs of the text in order.
+	Paragraphs []*Paragraph
+
+	// Free are the free modifiers that are not part of any paragraph.
+	Free []*Free
+
+	// Tree is the parse tree node from which the Text was converted.
+	Tree *peg.Node
+}
+
+// A Paragraph is a sequence of sentences.
+type Paragraph struct {
+	// Sentences are the sentences of the paragraph in order.
+	Sentences []*Sentence
+
+	// Free are the free modifiers that are not part of any sentence.
+	Free []*Free
+
+	// Tree is the parse tree node from which the Paragraph was converted.
+	Tree *peg.Node
+}
+
+// A Sentence is a bridi, a fragment, or a tu'e … tu'u group of sentences.
+type Sentence struct {
+	// Connective is the ijek connective joining the sentence
+	// to the previous sentence of the paragraph.
+	// It is nil if the sentence follows a plain .i or begins the paragraph.
+	Connective *Connective
+
+	// Prenex are the terms of a prenex, before zo'u.
+	Prenex []*Term
+
+	// Terms are the terms before the selbri.
+	Terms []*Term
+
+	// Tail is the bridi tail of the sentence.
+	// Tail is nil for fragments and groups.
+	Tail *BridiTail
+
+	// Tag is the tag of a tu'e … tu'u group, or nil if there is none.
+	Tag *Tag
+
+	// Group is the text of a tu'e … tu'u group, or nil if the sentence is not a group.
+	// A prenex over several connected sentences is also represented as a group.
+	Group *Text
+
+	// Fragment is whether the sentence is a fragment.
+	// Only the Prenex and Terms fields of fragments are set;
+	// other kinds of fragments are available only through Tree.
+	Fragment bool
+
+	// Free are the free modifiers within the sentence.
+	Free []*Free
+
+	// Tree is the parse tree node from which the Sentence was converted.
+	Tree *peg.Node
+}
+
+// A BridiTail is a selbri and its trailing terms,
+// or a logical connection of bridi tails.
+type BridiTail struct {
+	// Head are terms appearing within the bridi tail before the selbri.
+	// Only some dialects allow such terms.
+	Head []*Term
+
+	// Selbri is the selbri of a simple bridi tail.
+	// It is nil for connected bridi tails.
+	Selbri *Selbri
+
+	// Terms are the terms after the selbri.
+	// For connected bridi tails, these are the tail terms shared by all of Tails.
+	Terms []*Term
+
+	// Connective is the connective of a connected bridi tail,
+	// or nil for a simple bridi tail.
+	Connective *Connective
+
+	// Tails are the operands of an afterthought connection (gi'e and similar).
+	Tails []*BridiTail
+
+	// Sentences are the operands of a forethought connection (ge … gi and similar).
+	Sentences []*Sentence
+
+	// Tree is the parse tree node from which the BridiTail was converted.
+	Tree *peg.Node
+}
+
+// A Term is a sumti, a tagged sumti, a negation term, or a termset.
+type Term struct {
+	// Tag is the tense or modal tag of the term, or nil if there is none.
+	Tag *Tag
+
+	// FA is the FA place tag of the term, or the empty string if there is none.
+	FA string
+
+	// NA is the NA word of a na ku term, or the empty string if there is none.
+	NA string
+
+	// Sumti is the sumti of the term.
+	// It is nil for tags closed by ku, na ku terms, and termsets.
+	Sumti *Sumti
+
+	// Connective is the connective of a termset or connected term,
+	// or nil if there is none.
+	Connective *Connective
+
+	// Terms are the terms of a termset or connected term.
+	Terms []*Term
+
+	// Tree is the parse tree node from which the Term was converted.
+	Tree *peg.Node
+}
+
+// A SumtiKind is the kind of a Sumti.
+type SumtiKind int
+
+// The kinds of sumti.
+const (
+	// ProSumti is a pro-sumti, such as mi or ko'a.
+	ProSumti SumtiKind = iota
+	// DescriptionSumti is a description, such as lo gerku, or a quantified selbri, such as re gerku ku.
+	DescriptionSumti
+	// NameSumti is a name, such as la .djan.
+	NameSumti
+	// QuoteSumti is a quotation, such as lu … li'u or zoi.
+	QuoteSumti
+	// NumberSumti is a li number or a string of lerfu.
+	NumberSumti
+	// QualifiedSumti is a qualified sumti, such as la'e di'u.
+	QualifiedSumti
+	// ConnectedSumti is a logical connection of sumti.
+	ConnectedSumti
+)
+
+// A Sumti is a Lojban argument.
+type Sumti struct {
+	// Kind is the kind of the sumti.
+	Kind SumtiKind
+
+	// Quantifier is the outer quantifier, or the empty string if there is none.
+	Quantifier string
+
+	// Article is the LE, LA, LI, LAhE, or NAhE word of the sumti,
+	// or the empty string if there is none.
+	Article string
+
+	// Words are the words of a ProSumti or NumberSumti.
+	Words []string
+
+	// Names are the cmevla of a NameSumti.
+	Names []string
+
+	// Possessor is the possessive sumti of a description, such as mi in le mi gerku.
+	Possessor *Sumti
+
+	// InnerQuantifier is the quantifier inside a description, such as re in lo re gerku.
+	InnerQuantifier string
+
+	// Selbri is the selbri of a description.
+	Selbri *Selbri
+
+	// Inner is the sumti of a QualifiedSumti
+	// or the quantified sumti of a description such as lo re lo gerku.
+	Inner *Sumti
+
+	// Quote is the quotation of a QuoteSumti.
+	Quote *Quote
+
+	// RelativeClauses are the relative clauses attached to the sumti.
+	RelativeClauses []*RelativeClause
+
+	// Connective is the connective of a ConnectedSumti.
+	Connective *Connective
+
+	// Sumti are the operands of a ConnectedSumti.
+	Sumti []*Sumti
+
+	// Free are the free modifiers within the sumti.
+	Free []*Free
+
+	// Tree is the parse tree node from which the Sumti was converted.
+	Tree *peg.Node
+}
+
+// A Selbri is a Lojban predicate.
+type Selbri struct {
+	// Tag is the tense or modal tag of the selbri, or nil if there is none.
+	Tag *Tag
+
+	// NA are the NA words negating the selbri.
+	NA []string
+
+	// Tanru is the tanru of the selbri.
+	Tanru *Tanru
+
+	// Tree is the parse tree node from which the Selbri was converted.
+	Tree *peg.Node
+}
+
+// A Tanru is a sequence of tanru units, each modifying the next,
+// or a logical connection of tanru.
+//
+// Inversions with co are normalized,
+// so that the last unit of Units is always the head of the tanru.
+type Tanru struct {
+	// Units are the units of the tanru.
+	// The last unit is the head.
+	Units []*TanruUnit
+
+	// Connective is the connective of a connected tanru,
+	// or nil for a simple tanru.
+	Connective *Connective
+
+	// Tanru are the operands of a connected tanru.
+	Tanru []*Tanru
+
+	// Tree is the parse tree node from which the Tanru was converted.
+	Tree *peg.Node
+}
+
+// A TanruUnit is a single unit of a tanru.
+type TanruUnit struct {
+	// Word is the brivla, GOhA, or other word of a simple unit.
+	Word string
+
+	// Selmaho is the selma'o of Word, such as BRIVLA, GOhA, or MOI.
+	Selmaho string
+
+	// Conversions are the SE and JAI words applied to the unit, outermost first.
+	Conversions []string
+
+	// NAhE are the NAhE words applied to the unit, outermost first.
+	NAhE []string
+
+	// Group is the tanru of a ke … ke'e or bo group.
+	Group *Tanru
+
+	// Abstractor are the NU words of an abstraction.
+	Abstractor []string
+
+	// Abstraction is the sentence of an abstraction.
+	Abstraction *Sentence
+
+	// Sumti is the sumti of a me … me'u unit.
+	Sumti *Sumti
+
+	// Links are the be … bei … be'o linked arguments, in order.
+	Links []*Term
+
+	// Tree is the parse tree node from which the TanruUnit was converted.
+	Tree *peg.Node
+}
+
+// A Tag is a tense or modal tag.
+type Tag struct {
+	// Words are the words of the tag in order.
+	Words []string
+
+	// Selbri is the selbri of a fi'o … fe'u modal, or nil if there is none.
+	Selbri *Selbri
+
+	// Tree is the parse tree node from which the Tag was converted.
+	Tree *peg.Node
+}
+
+// A RelativeClause is a relative clause or relative phrase.
+type RelativeClause struct {
+	// Word is the NOI or GOI word introducing the clause.
+	Word string
+
+	// Sentence is the sentence of a NOI relative clause.
+	Sentence *Sentence
+
+	// Term is the term of a GOI relative phrase.
+	Term *Term
+
+	// Tree is the parse tree node from which the RelativeClause was converted.
+	Tree *peg.Node
+}
+
+// A Connective is a logical or non-logical connective.
+type Connective struct {
+	// Selmaho is the selma'o of the connective's main word,
+	// such as A, GA, GIhA, GUhA, JA, JOI, or BIhI.
+	// Selmaho is the empty string if there is no main word,
+	// as in a bare stag bo.
+	Selmaho string
+
+	// Word is the main word of the connective, such as e or gi'e.
+	Word string
+
+	// NA is whether the connective negates its left operand.
+	NA bool
+
+	// SE is the SE conversion of the connective, or the empty string if there is none.
+	SE string
+
+	// NAI is whether the connective negates its right operand.
+	// The right operand of a forethought connective is the second,
+	// negated by the nai of its gi, as in {ge … gi nai …}.
+	NAI bool
+
+	// NAIFirst is whether a forethought connective negates its first operand,
+	// as in {ge nai … gi …}.
+	NAIFirst bool
+
+	// Tag is the tag qualifying the connective, or nil if there is none.
+	Tag *Tag
+
+	// Tree is the parse tree node from which the Connective was converted.
+	Tree *peg.Node
+}
+
+// A Free is a free modifier:
+// a vocative, a sei metalinguistic comment, a to … toi parenthetical,
+// a MAI utterance ordinal, or a subscript.
+type Free struct {
+	// Selmaho is the selma'o of the first word of the free modifier.
+	Selmaho string
+
+	// Words are the cmavo and cmevla of the free modifier
+	// that are not part of its Terms, Sumti, Selbri, or Text.
+	Words []string
+
+	// Terms are the terms of a sei comment.
+	Terms []*Term
+
+	// Sumti is the sumti of a vocative, or nil if there is none.
+	Sumti *Sumti
+
+	// Selbri is the selbri of a vocative or sei comment, or nil if there is none.
+	Selbri *Selbri
+
+	// Text is the text of a to … toi parenthetical, or nil if there is none.
+	Text *Text
+
+	// Tree is the parse tree node from which the Free was converted.
+	Tree *peg.Node
+}
+
+// A Quote is the quotation of a QuoteSumti.
+type Quote struct {
+	// Word is the quoting word, such as lu, zo, zoi, or lo'u.
+	Word string
+
+	// Delimiter is the delimiting word of a zoi quotation.
+	Delimiter string
+
+	// Content is the unparsed content of a zo, zoi, or lo'u quotation.
+	Content string
+
+	// Text is the parsed text of a lu … li'u quotation.
+	Text *Text
+
+	// Tree is the parse tree node from which the Quote was converted.
+	Tree *peg.Node
+}
@@ -0,0 +1,1130 @@
+package ast
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+)
+
+// An UnsupportedError is returned by Convert
+// for a construct that cannot be represented as an AST.
+type UnsupportedError struct {
+	// Node is the parse tree node of the unsupported construct.
+	Node *peg.Node
+}
+
+func (err *UnsupportedError) Error() string {
+	text := strings.Trim(err.Node.Text, parser.SpaceChars)
+	return "ast: unsupported " + err.Node.Name + " " + strconv.Quote(text)
+}
+
+// Convert converts a parse tree of the camxes, camxes-beta, or ilmentufa dialect to a Text.
+//
+// The tree may have been passed through parser.RemoveMorphology, parser.RemoveSpace,
+// and parser.AddElidedTerminators,
+// but not through parser.CollapseLists, which discards the rule names Convert relies on.
+//
+// If the tree contains a construct that cannot be represented,
+// Convert returns the Text converted so far and an *UnsupportedError.
+func Convert(n *peg.Node) (*Text, error) {
+	var c converter
+	t := c.text(n)
+	if c.err != nil {
+		return t, c.err
+	}
+	return t, nil
+}
+
+type converter struct {
+	// free is the slice to which free modifiers are added.
+	free *[]*Free
+	err  *UnsupportedError
+}
+
+func (c *converter) unsupported(n *peg.Node) {
+	if c.err == nil {
+		c.err = &UnsupportedError{Node: n}
+	}
+}
+
+// sink directs free modifiers to fs until the returned function is called.
+func (c *converter) sink(fs *[]*Free) func() {
+	prev := c.free
+	c.free = fs
+	return func() { c.free = prev }
+}
+
+func (c *converter) addFree(n *peg.Node) {
+	if c.free == nil {
+		var fs []*Free
+		c.free = &fs
+	}
+	*c.free = append(*c.free, c.freeMod(n))
+}
+
+func (c *converter) text(n *peg.Node) *Text {
+	t := &Text{Tree: n}
+	defer c.sink(&t.Free)()
+	c.textInto(t, n)
+	return t
+}
+
+func (c *converter) textInto(t *Text, n *peg.Node) {
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "text_eof", "text", "paragraphs", "intro_null", "text_part", "faho_clause":
+			c.textInto(t, k)
+		case "paragraph":
+			t.Paragraphs = append(t.Paragraphs, c.paragraph(k))
+		case "free":
+			c.addFree(k)
+		case "I", "NIhO", "FAhO":
+			// Text-level separators carry no structure.
+		default:
+			c.unsupported(k)
+		}
+	}
+}
+
+func (c *converter) paragraph(n *peg.Node) *Paragraph {
+	p := &Paragraph{Tree: n}
+	defer c.sink(&p.Free)()
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "statement":
+			p.Sentences = append(p.Sentences, c.statement(k)...)
+		case "fragment":
+			p.Sentences = append(p.Sentences, c.fragment(k))
+		case "free":
+			c.addFree(k)
+		case "I":
+		default:
+			c.unsupported(k)
+		}
+	}
+	return p
+}
+
+// statement returns the sentences of a statement.
+// Sentences connected by ijek connectives have their Connective set.
+func (c *converter) statement(n *peg.Node) []*Sentence {
+	var ss []*Sentence
+	var prenex []*Term
+	var conn *Connective
+	var tag *Tag
+	add := func(s ...*Sentence) {
+		if len(s) > 0 && conn != nil {
+			s[0].Connective = conn
+			conn = nil
+		}
+		ss = append(ss, s...)
+	}
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "statement":
+			add(c.statement(k)...)
+		case "sentence":
+			add(c.sentence(k))
+		case "prenex":
+			prenex = c.prenex(k)
+		case "I", "jek", "joik", "joik_jek", "stag", "BO":
+			if conn == nil {
+				conn = &Connective{Tree: k}
+			}
+			c.connectiveInto(conn, k)
+		case "tag":
+			tag = c.tag(k)
+		case "text":
+			s := &Sentence{Tag: tag, Group: c.text(k), Tree: n}
+			tag = nil
+			add(s)
+		case "TUhE", "TUhU", "IAU":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	for _, s := range ss {
+		if s.Connective != nil && s.Connective.Selmaho == "" && s.Connective.Tag == nil {
+			// A plain .i bo is not a connective.
+			s.Connective = nil
+		}
+	}
+	if prenex == nil {
+		return ss
+	}
+	if len(ss) == 1 && ss[0].Prenex == nil {
+		ss[0].Prenex = prenex
+		return ss
+	}
+	p := &Paragraph{Sentences: ss, Tree: n}
+	return []*Sentence{{
+		Prenex: prenex,
+		Group:  &Text{Paragraphs: []*Paragraph{p}, Tree: n},
+		Tree:   n,
+	}}
+}
+
+func (c *converter) prenex(n *peg.Node) []*Term {
+	var ts []*Term
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "terms":
+			ts = append(ts, c.terms(k)...)
+		case "ZOhU":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	return ts
+}
+
+func (c *converter) fragment(n *peg.Node) *Sentence {
+	s := &Sentence{Fragment: true, Tree: n}
+	defer c.sink(&s.Free)()
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "prenex":
+			s.Prenex = c.prenex(k)
+		case "terms":
+			s.Terms = append(s.Terms, c.terms(k)...)
+		case "links", "linkargs":
+			s.Terms = append(s.Terms, c.links(k)...)
+		case "free":
+			c.addFree(k)
+		}
+	}
+	return s
+}
+
+func (c *converter) subsentence(n *peg.Node) *Sentence {
+	var prenex []*Term
+	var s *Sentence
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "prenex":
+			prenex = c.prenex(k)
+		case "subsentence":
+			s = c.subsentence(k)
+		case "sentence":
+			s = c.sentence(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	if s == nil {
+		return &Sentence{Prenex: prenex, Tree: n}
+	}
+	if prenex != nil {
+		s.Prenex = append(prenex, s.Prenex...)
+		s.Tree = n
+	}
+	return s
+}
+
+func (c *converter) sentence(n *peg.Node) *Sentence {
+	s := &Sentence{Tree: n}
+	defer c.sink(&s.Free)()
+	var conn *Connective
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "terms":
+			s.Terms = append(s.Terms, c.terms(k)...)
+		case "bridi_tail":
+			s.Tail = joinTails(s.Tail, conn, c.bridiTail(k), n)
+			conn = nil
+		case "joik_jek", "stag":
+			if conn == nil {
+				conn = &Connective{Tree: k}
+			}
+			c.connectiveInto(conn, k)
+		case "CU", "KE", "KEhE":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	return s
+}
+
+func joinTails(left *BridiTail, conn *Connective, right *BridiTail, n *peg.Node) *BridiTail {
+	if left == nil {
+		return right
+	}
+	return &BridiTail{
+		Connective: conn,
+		Tails:      []*BridiTail{left, right},
+		Tree:       n,
+	}
+}
+
+func (c *converter) bridiTail(n *peg.Node) *BridiTail {
+	var t *BridiTail
+	var conn *Connective
+	var head []*Term
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "bridi_tail":
+			t = joinTails(t, conn, c.bridiTail(k), n)
+			conn = nil
+		case "gek_sentence":
+			t = joinTails(t, conn, c.gekSentence(k), n)
+			conn = nil
+		case "gihek", "joik_jek", "stag":
+			if conn == nil {
+				conn = &Connective{Tree: k}
+			}
+			c.connectiveInto(conn, k)
+		case "terms":
+			head = append(head, c.terms(k)...)
+		case "selbri":
+			t = &BridiTail{Head: head, Selbri: c.selbri(k), Tree: n}
+			head = nil
+		case "tail_terms":
+			if t == nil {
+				c.unsupported(k)
+				continue
+			}
+			t.Terms = append(t.Terms, c.tailTerms(k)...)
+		case "CU", "KE", "KEhE", "BO":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	if t == nil {
+		c.unsupported(n)
+		return &BridiTail{Tree: n}
+	}
+	return t
+}
+
+func (c *converter) gekSentence(n *peg.Node) *BridiTail {
+	t := &BridiTail{Tree: n}
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "gek":
+			t.Connective = c.connective(k)
+		case "gik":
+			c.connectiveInto(t.Connective, k)
+		case "subsentence":
+			t.Sentences = append(t.Sentences, c.subsentence(k))
+		case "tail_terms":
+			t.Terms = append(t.Terms, c.tailTerms(k)...)
+		case "free":
+			c.addFree(k)
+		default:
+			// Tagged or negated ke … ke'e forethought sentences.
+			c.unsupported(k)
+		}
+	}
+	return t
+}
+
+func (c *converter) tailTerms(n *peg.Node) []*Term {
+	var ts []*Term
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "terms":
+			ts = append(ts, c.terms(k)...)
+		case "VAU":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	return ts
+}
+
+func (c *converter) terms(n *peg.Node) []*Term {
+	var ts []*Term
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "terms":
+			ts = append(ts, c.terms(k)...)
+		case "term", "nonabs_term":
+			ts = append(ts, c.term(k))
+		default:
+			// Afterthought termsets with pe'e and ce'e.
+			c.unsupported(k)
+		}
+	}
+	return ts
+}
+
+func (c *converter) term(n *peg.Node) *Term {
+	var t *Term
+	var conn *Connective
+	simple := &Term{Tree: n}
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "term", "nonabs_term", "tag_term", "nonabs_tag_term", "nontag_adverbial":
+			u := c.term(k)
+			switch {
+			case t == nil:
+				t = u
+			case conn != nil:
+				t = &Term{Connective: conn, Terms: []*Term{t, u}, Tree: n}
+				conn = nil
+			default:
+				c.unsupported(k)
+			}
+		case "termset":
+			t = c.termset(k)
+		case "joik_ek", "stag":
+			if conn == nil {
+				conn = &Connective{Tree: k}
+			}
+			c.connectiveInto(conn, k)
+		case "sumti":
+			simple.Sumti = c.sumti(k)
+			t = simple
+		case "tag":
+			if fa := faTag(k); fa != "" {
+				// Some dialects parse FA as a tense_modal.
+				simple.FA = fa
+			} else {
+				simple.Tag = c.tag(k)
+			}
+			t = simple
+		case "FA":
+			simple.FA = word(k)
+			t = simple
+		case "NA":
+			simple.NA = word(k)
+			t = simple
+		case "KU", "BO":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	if t == nil {
+		c.unsupported(n)
+		return simple
+	}
+	return t
+}
+
+func (c *converter) termset(n *peg.Node) *Term {
+	t := &Term{Tree: n}
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "gek_termset":
+			return c.termset(k)
+		case "gek":
+			t.Connective = c.connective(k)
+		case "gik":
+			c.connectiveInto(t.Connective, k)
+		case "terms":
+			t.Terms = append(t.Terms, &Term{Terms: c.terms(k), Tree: k})
+		case "terms_gik_terms":
+			left, right := c.termsGikTerms(k)
+			t.Terms = append(t.Terms,
+				&Term{Terms: left, Tree: k},
+				&Term{Terms: right, Tree: k})
+		case "NUhI", "NUhU", "KE", "KEhE":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	if t.Connective == nil && len(t.Terms) == 1 {
+		t.Terms = t.Terms[0].Terms
+	}
+	return t
+}
+
+// termsGikTerms returns the terms on either side of the gi
+// in the nested terms_gik_terms rule.
+func (c *converter) termsGikTerms(n *peg.Node) ([]*Term, []*Term) {
+	var left, right []*Term
+	ks := kids(n)
+	for i, k := range ks {
+		switch parser.Rule(k.Name) {
+		case "nonabs_term", "term":
+			if i == 0 {
+				left = append(left, c.term(k))
+			} else {
+				right = append(right, c.term(k))
+			}
+		case "terms_gik_terms":
+			l, r := c.termsGikTerms(k)
+			left = append(left, l...)
+			right = append(r, right...)
+		case "gik":
+		default:
+			c.unsupported(k)
+		}
+	}
+	return left, right
+}
+
+func (c *converter) sumti(n *peg.Node) *Sumti {
+	var s *Sumti
+	var conn *Connective
+	var quant string
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "sumti":
+			u := c.sumti(k)
+			switch {
+			case s == nil:
+				s = u
+			case s.Kind == QualifiedSumti && s.Inner == nil:
+				s.Inner = u
+			case conn != nil:
+				s = &Sumti{
+					Kind:       ConnectedSumti,
+					Connective: conn,
+					Sumti:      []*Sumti{s, u},
+					Tree:       n,
+				}
+				conn = nil
+			default:
+				c.unsupported(k)
+			}
+		case "joik_ek", "gek", "stag":
+			if conn == nil {
+				conn = &Connective{Tree: k}
+			}
+			c.connectiveInto(conn, k)
+		case "gik":
+			c.connectiveInto(conn, k)
+		case "quantifier":
+			quant = words(k)
+		case "selbri":
+			s = &Sumti{
+				Kind:   DescriptionSumti,
+				Selbri: c.selbri(k),
+				Tree:   n,
+			}
+		case "relative_clauses":
+			if s == nil {
+				c.unsupported(k)
+				continue
+			}
+			s.RelativeClauses = append(s.RelativeClauses, c.relativeClauses(k)...)
+		case "ZO", "ZOI", "ZOhOI", "LOhU", "LU":
+			s = &Sumti{Kind: QuoteSumti, Quote: c.quote(n), Tree: n}
+			return s
+		case "lerfu_string":
+			s = &Sumti{Kind: NumberSumti, Words: strings.Fields(words(k)), Tree: n}
+		case "li_clause":
+			s = &Sumti{Kind: NumberSumti, Article: "li", Tree: k}
+			for _, kk := range kids(k) {
+				if parser.Rule(kk.Name) == "mex" {
+					s.Words = strings.Fields(words(kk))
+				}
+			}
+		case "KOhA":
+			s = &Sumti{Kind: ProSumti, Words: []string{word(k)}, Tree: n}
+		case "LAhE", "NAhE":
+			if s == nil {
+				s = &Sumti{Kind: QualifiedSumti, Tree: n}
+			}
+			s.Article = joinWords(s.Article, word(k))
+		case "LA", "LE":
+			s = &Sumti{Kind: NameSumti, Article: word(k), Tree: n}
+		case "CMEVLA":
+			if s == nil {
+				c.unsupported(k)
+				continue
+			}
+			s.Names = append(s.Names, word(k))
+		case "sumti_tail":
+			s.Kind = DescriptionSumti
+			c.sumtiTail(s, k)
+		case "term":
+			if s == nil || s.Kind != QualifiedSumti {
+				c.unsupported(k)
+				continue
+			}
+			t := c.term(k)
+			if t.Sumti == nil {
+				c.unsupported(k)
+				continue
+			}
+			s.Inner = t.Sumti
+		case "VUhO", "KU", "BO", "LUhU", "BOI", "KEhE", "KE":
+		case "free":
+			if s == nil {
+				c.addFree(k)
+				continue
+			}
+			s.Free = append(s.Free, c.freeMod(k))
+		default:
+			c.unsupported(k)
+		}
+	}
+	if s == nil {
+		c.unsupported(n)
+		return &Sumti{Tree: n}
+	}
+	if s.Kind == QualifiedSumti && s.Inner == nil {
+		// The sumti following LAhE is parsed into s by the sumti case.
+		c.unsupported(n)
+	}
+	if quant != "" {
+		s.Quantifier = quant
+	}
+	return s
+}
+
+func (c *converter) sumtiTail(s *Sumti, n *peg.Node) {
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "sumti_tail":
+			c.sumtiTail(s, k)
+		case "sumti":
+			if s.Selbri == nil && s.InnerQuantifier == "" {
+				s.Possessor = c.sumti(k)
+			} else {
+				s.Inner = c.sumti(k)
+			}
+		case "quantifier":
+			s.InnerQuantifier = words(k)
+		case "selbri":
+			s.Selbri = c.selbri(k)
+		case "relative_clauses":
+			rs := c.relativeClauses(k)
+			if s.Possessor != nil && s.Selbri == nil && s.InnerQuantifier == "" {
+				s.Possessor.RelativeClauses = append(s.Possessor.RelativeClauses, rs...)
+				continue
+			}
+			s.RelativeClauses = append(s.RelativeClauses, rs...)
+		default:
+			c.unsupported(k)
+		}
+	}
+}
+
+func (c *converter) quote(n *peg.Node) *Quote {
+	q := &Quote{Tree: n}
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "ZO", "ZOhOI", "LOhU":
+			ws := wordList(k)
+			if len(ws) > 0 {
+				q.Word = ws[0]
+			}
+			if parser.Rule(k.Name) == "LOhU" && len(ws) > 0 {
+				// Drop the closing le'u.
+				ws = ws[:len(ws)-1]
+			}
+			if len(ws) > 1 {
+				q.Content = strings.Join(ws[1:], " ")
+			}
+			if parser.Rule(k.Name) == "ZOhOI" {
+				q.Content = zoiContent(k)
+			}
+		case "ZOI":
+			q.Word = word(k)
+			var zoi []string
+			walkNames(k, func(n *peg.Node) bool {
+				switch n.Name {
+				case "zoi_open":
+					q.Delimiter = strings.Trim(n.Text, parser.SpaceChars)
+					return false
+				case "zoi_word":
+					zoi = append(zoi, n.Text)
+					return false
+				}
+				return true
+			})
+			q.Content = strings.Join(zoi, " ")
+		case "LU":
+			q.Word = word(k)
+		case "text":
+			q.Text = c.text(k)
+		case "LIhU":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	return q
+}
+
+// zoiContent returns the text of the non-Lojban word quoted by zo'oi.
+func zoiContent(n *peg.Node) string {
+	var s string
+	walkNames(n, func(n *peg.Node) bool {
+		if n.Name == "zohoi_word" {
+			s = strings.Trim(n.Text, parser.SpaceChars)
+			return false
+		}
+		return true
+	})
+	return s
+}
+
+func (c *converter) relativeClauses(n *peg.Node) []*RelativeClause {
+	var rs []*RelativeClause
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "relative_clauses":
+			rs = append(rs, c.relativeClauses(k)...)
+		case "relative_clause":
+			rs = append(rs, c.relativeClause(k))
+		case "ZIhE":
+		case "free":
+			c.addFree(k)
+		default:
+			// Connected relative clauses.
+			c.unsupported(k)
+		}
+	}
+	return rs
+}
+
+func (c *converter) relativeClause(n *peg.Node) *RelativeClause {
+	r := &RelativeClause{Tree: n}
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "relative_clause":
+			return c.relativeClause(k)
+		case "NOI", "GOI":
+			r.Word = word(k)
+		case "subsentence":
+			r.Sentence = c.subsentence(k)
+		case "term", "nonabs_term":
+			r.Term = c.term(k)
+		case "KUhO", "GEhU":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	return r
+}
+
+func (c *converter) selbri(n *peg.Node) *Selbri {
+	s := &Selbri{Tree: n}
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "selbri":
+			if isTanru(k) {
+				s.Tanru = c.tanru(k)
+				continue
+			}
+			inner := c.selbri(k)
+			if s.Tag != nil && inner.Tag != nil {
+				c.unsupported(k)
+			}
+			if inner.Tag != nil {
+				s.Tag = inner.Tag
+			}
+			s.NA = append(s.NA, inner.NA...)
+			s.Tanru = inner.Tanru
+		case "tag":
+			s.Tag = c.tag(k)
+		case "NA":
+			s.NA = append(s.NA, word(k))
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	if s.Tanru == nil {
+		c.unsupported(n)
+		s.Tanru = &Tanru{Tree: n}
+	}
+	return s
+}
+
+// isTanru returns whether n is one of the selbri rules below the level of tags and negation.
+func isTanru(n *peg.Node) bool {
+	switch n.Name {
+	case "selbri_2", "selbri_3", "selbri_4", "selbri_5", "selbri_6":
+		return true
+	}
+	return false
+}
+
+func (c *converter) tanru(n *peg.Node) *Tanru {
+	ks := kids(n)
+	if len(ks) == 1 {
+		switch parser.Rule(ks[0].Name) {
+		case "selbri":
+			return c.selbriTanru(ks[0])
+		case "tanru_unit":
+			return &Tanru{Units: []*TanruUnit{c.tanruUnit(ks[0])}, Tree: n}
+		}
+	}
+	switch n.Name {
+	case "selbri_2":
+		// selbri_3 co selbri_2: the right-hand side modifies the left.
+		var units []*TanruUnit
+		for _, k := range ks {
+			if parser.Rule(k.Name) == "selbri" {
+				units = append([]*TanruUnit{unit(c.selbriTanru(k))}, units...)
+			} else if parser.Rule(k.Name) != "CO" && parser.Rule(k.Name) != "free" {
+				c.unsupported(k)
+			}
+		}
+		return &Tanru{Units: units, Tree: n}
+	case "selbri_3":
+		t := &Tanru{Tree: n}
+		for _, k := range ks {
+			t.Units = append(t.Units, unit(c.selbriTanru(k)))
+		}
+		return t
+	}
+	var t *Tanru
+	var conn *Connective
+	var bo bool
+	for _, k := range ks {
+		var u *Tanru
+		switch parser.Rule(k.Name) {
+		case "selbri":
+			u = c.selbriTanru(k)
+		case "tanru_unit":
+			u = &Tanru{Units: []*TanruUnit{c.tanruUnit(k)}, Tree: k}
+		case "joik_jek", "jek", "joik", "guhek", "stag":
+			if conn == nil {
+				conn = &Connective{Tree: k}
+			}
+			c.connectiveInto(conn, k)
+			continue
+		case "gik":
+			c.connectiveInto(conn, k)
+			continue
+		case "BO":
+			bo = true
+			continue
+		case "KE", "KEhE":
+			continue
+		case "free":
+			c.addFree(k)
+			continue
+		default:
+			c.unsupported(k)
+			continue
+		}
+		switch {
+		case t == nil:
+			t = u
+		case conn != nil:
+			t = &Tanru{Connective: conn, Tanru: []*Tanru{t, u}, Tree: n}
+			conn = nil
+		case bo:
+			// tanru_unit bo selbri_6 groups the units into a single unit.
+			t = &Tanru{Units: []*TanruUnit{unit(t), unit(u)}, Tree: n}
+		default:
+			c.unsupported(k)
+		}
+		bo = false
+	}
+	if t == nil {
+		c.unsupported(n)
+		return &Tanru{Tree: n}
+	}
+	return t
+}
+
+// selbriTanru returns the Tanru of a selbri rule.
+// Tags and negation are not supported within tanru.
+func (c *converter) selbriTanru(n *peg.Node) *Tanru {
+	if isTanru(n) {
+		return c.tanru(n)
+	}
+	s := c.selbri(n)
+	if s.Tag != nil || len(s.NA) > 0 {
+		c.unsupported(n)
+	}
+	return s.Tanru
+}
+
+// unit returns a Tanru as a single tanru unit.
+func unit(t *Tanru) *TanruUnit {
+	if len(t.Units) == 1 && t.Connective == nil {
+		return t.Units[0]
+	}
+	return &TanruUnit{Group: t, Tree: t.Tree}
+}
+
+func (c *converter) tanruUnit(n *peg.Node) *TanruUnit {
+	u := &TanruUnit{Tree: n}
+	for _, k := range kids(n) {
+		switch r := parser.Rule(k.Name); r {
+		case "tanru_unit":
+			inner := c.tanruUnit(k)
+			if u.Word != "" || u.Group != nil || u.Abstraction != nil || u.Sumti != nil {
+				// cei assignments are not supported.
+				c.unsupported(k)
+				continue
+			}
+			inner.Conversions = append(u.Conversions, inner.Conversions...)
+			inner.NAhE = append(u.NAhE, inner.NAhE...)
+			inner.Links = append(inner.Links, u.Links...)
+			inner.Tree = n
+			u = inner
+		case "linkargs":
+			u.Links = append(u.Links, c.links(k)...)
+		case "BRIVLA", "GOhA", "GOhOI", "MEhOI", "NUhA":
+			u.Word, u.Selmaho = word(k), r
+		case "RAhO":
+			u.Word = joinWords(u.Word, word(k))
+		case "MOI":
+			u.Word, u.Selmaho = joinWords(u.Word, word(k)), r
+		case "number", "lerfu_string", "mex", "mex_operator", "operator":
+			u.Word = joinWords(u.Word, words(k))
+		case "selbri":
+			u.Group = c.selbriTanru(k)
+		case "SE", "JAI":
+			u.Conversions = append(u.Conversions, word(k))
+		case "NAhE":
+			u.NAhE = append(u.NAhE, word(k))
+		case "ME":
+			u.Selmaho = r
+		case "sumti":
+			u.Sumti = c.sumti(k)
+		case "NU":
+			u.Abstractor = append(u.Abstractor, word(k))
+		case "NAI":
+			if len(u.Abstractor) > 0 {
+				u.Abstractor[len(u.Abstractor)-1] += word(k)
+			}
+		case "subsentence":
+			u.Abstraction = c.subsentence(k)
+		case "KE", "KEhE", "MEhU", "KEI", "joik_jek":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	return u
+}
+
+func (c *converter) links(n *peg.Node) []*Term {
+	var ts []*Term
+	for _, k := range kids(n) {
+		switch parser.Rule(k.Name) {
+		case "linkargs", "links":
+			ts = append(ts, c.links(k)...)
+		case "term", "nonabs_term":
+			ts = append(ts, c.term(k))
+		case "BE", "BEI", "BEhO":
+		case "free":
+			c.addFree(k)
+		default:
+			c.unsupported(k)
+		}
+	}
+	return ts
+}
+
+func (c *converter) tag(n *peg.Node) *Tag {
+	t := &Tag{Tree: n}
+	c.tagInto(t, n)
+	return t
+}
+
+func (c *converter) tagInto(t *Tag, n *peg.Node) {
+	for _, k := range kids(n) {
+		switch r := parser.Rule(k.Name); {
+		case r == "selbri":
+			t.Selbri = c.selbri(k)
+		case r == "free":
+			c.addFree(k)
+		case r == "FEhU":
+		case parser.IsWordRule(r):
+			t.Words = append(t.Words, word(k))
+		default:
+			c.tagInto(t, k)
+		}
+	}
+}
+
+// faTag returns the FA word of a tag consisting solely of an FA word,
+// or the empty string if the tag is anything else.
+func faTag(n *peg.Node) string {
+	ks := kids(n)
+	switch {
+	case len(ks) != 1:
+		return ""
+	case parser.Rule(ks[0].Name) == "FA":
+		return word(ks[0])
+	case parser.IsWordRule(parser.Rule(ks[0].Name)):
+		return ""
+	}
+	return faTag(ks[0])
+}
+
+// connective returns the Connective of a connective rule, such as ek, gihek, or gek.
+func (c *converter) connective(n *peg.Node) *Connective {
+	conn := &Connective{Tree: n}
+	c.connectiveInto(conn, n)
+	return conn
+}
+
+func (c *converter) connectiveInto(conn *Connective, n *peg.Node) {
+	if conn == nil {
+		c.unsupported(n)
+		return
+	}
+	switch r := parser.Rule(n.Name); r {
+	case "NA":
+		conn.NA = true
+		return
+	case "SE":
+		conn.SE = word(n)
+		return
+	case "NAI":
+		conn.NAI = true
+		return
+	case "gek", "guhek":
+		// The NAI of a forethought connective negates its first operand,
+		// and that of its gik, converted separately, its second.
+		nai := conn.NAI
+		conn.NAI = false
+		for _, k := range kids(n) {
+			c.connectiveInto(conn, k)
+		}
+		conn.NAIFirst, conn.NAI = conn.NAI, nai
+		return
+	case "I", "GI", "BO", "KE", "gak", "guk":
+		return
+	case "stag", "tag":
+		conn.Tag = c.tag(n)
+		return
+	case "free":
+		c.addFree(n)
+		return
+	default:
+		if parser.IsWordRule(r) {
+			conn.Selmaho, conn.Word = r, word(n)
+			return
+		}
+	}
+	for _, k := range kids(n) {
+		c.connectiveInto(conn, k)
+	}
+}
+
+func (c *converter) freeMod(n *peg.Node) *Free {
+	f := &Free{Tree: n}
+	var walk func(*peg.Node)
+	walk = func(n *peg.Node) {
+		for _, k := range kids(n) {
+			switch r := parser.Rule(k.Name); {
+			case r == "terms":
+				f.Terms = append(f.Terms, c.terms(k)...)
+			case r == "sumti":
+				f.Sumti = c.sumti(k)
+			case r == "selbri":
+				f.Selbri = c.selbri(k)
+			case r == "text":
+				f.Text = c.text(k)
+			case r == "free":
+				c.addFree(k)
+			case r == "relative_clauses":
+				c.unsupported(k)
+			case parser.IsWordRule(r):
+				if f.Selmaho == "" {
+					f.Selmaho = r
+				}
+				if r == "CU" || r == "TOI" || r == "SEhU" || r == "DOhU" {
+					continue
+				}
+				f.Words = append(f.Words, word(k))
+			default:
+				walk(k)
+			}
+		}
+	}
+	walk(n)
+	return f
+}
+
+// kids returns the meaningful children of n.
+// The children of anonymous nodes are spliced in place of the node,
+// and empty nodes, spaces, elided terminators, and erased words are skipped.
+func kids(n *peg.Node) []*peg.Node {
+	var ks []*peg.Node
+	for _, k := range n.Kids {
+		switch {
+		case k.Text == "" && len(k.Kids) == 0:
+		case k.Name == "":
+			ks = append(ks, kids(k)...)
+		case skip(k.Name):
+		default:
+			ks = append(ks, k)
+		}
+	}
+	return ks
+}
+
+func skip(name string) bool {
+	switch name {
+	case "spaces", "initial_spaces", "dot_star", "EOF",
+		"si_clause", "SI_clause", "SA_clause", "su_clause", "SU_clause":
+		return true
+	}
+	return strings.HasSuffix(name, "_sa") || strings.HasSuffix(name, "_elidible") && parser.IsWordRule(parser.Rule(name))
+}
+
+// word returns the first word beneath n.
+func word(n *peg.Node) string {
+	if ws := wordList(n); len(ws) > 0 {
+		return ws[0]
+	}
+	return strings.Trim(n.Text, parser.SpaceChars)
+}
+
+// words returns the words beneath n, separated by spaces.
+func words(n *peg.Node) string {
+	return strings.Join(wordList(n), " ")
+}
+
+func joinWords(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + " " + b
+}
+
+// wordList returns the words beneath n,
+// excluding leading BAhE, trailing indicators, and spaces.
+func wordList(n *peg.Node) []string {
+	var ws []string
+	walkNames(n, func(n *peg.Node) bool {
+		switch {
+		case n.Name == "pre_clause" || n.Name == "post_clause" || strings.HasSuffix(n.Name, "_post") || skip(n.Name):
+			return false
+		case parser.IsWordRule(n.Name):
+			if w := strings.Trim(n.Text, parser.SpaceChars); w != "" {
+				ws = append(ws, w)
+			}
+			return false
+		}
+		return true
+	})
+	return ws
+}
+
+// walkNames calls f for n and its descendants in pre-order,
+// descending into a node's children only if f returns true.
+func walkNames(n *peg.Node, f func(*peg.Node) bool) {
+	if !f(n) {
+		return
+	}
+	for _, k := range n.Kids {
+		walkNames(k, f)
+	}
+}
@@ -0,0 +1,183 @@
+package ast_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"within.website/johaus/ast"
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func convert(t *testing.T, dialect, text string) (*ast.Text, error) {
+	t.Helper()
+	tree, err := parser.Parse(dialect, text)
+	if err != nil {
+		t.Fatalf("%s: Parse(%q) failed: %v", dialect, text, err)
+	}
+	parser.RemoveMorphology(tree)
+	parser.RemoveSpace(tree)
+	parser.AddElidedTerminators(tree)
+	return ast.Convert(tree)
+}
+
+func TestSumti(t *testing.T) {
+	tests := []struct {
+		// text is a sentence whose first term is the sumti.
+		text string
+		want string
+	}{
+		{text: "mi klama", want: "pro [mi]"},
+		{text: "ko'a klama", want: "pro [ko'a]"},
+		{text: "lo gerku cu klama", want: "description lo (gerku)"},
+		{text: "le mi gerku cu klama", want: "description le of pro [mi] (gerku)"},
+		{text: "lo re gerku cu klama", want: "description lo re (gerku)"},
+		{text: "re lo gerku cu klama", want: "description re lo (gerku)"},
+		{text: "re gerku ku klama", want: "description re (gerku)"},
+		{text: "lo re lo gerku cu klama", want: "description lo re of description lo (gerku)"},
+		{text: "lo gerku poi blabi cu klama", want: "description lo (gerku) poi"},
+		{text: "la .djan. cu klama", want: "name la [djan]"},
+		{text: "la .djan. .smit. cu klama", want: "name la [djan smit]"},
+		{text: "la barda cu klama", want: "description la (barda)"},
+		{text: "zoi gy hello gy cu valsi", want: "quote zoi gy \"hello\""},
+		{text: "zo klama cu valsi", want: "quote zo \"klama\""},
+		{text: "lo'u mi klama le'u cu valsi", want: "quote lo'u \"mi klama\""},
+		{text: "lu mi klama li'u cu bridi", want: "quote lu 1 paragraphs"},
+		{text: "li pa cu namcu", want: "number li [pa]"},
+		{text: "by cy klama", want: "number [by cy]"},
+		{text: "la'e di'u cu xamgu", want: "qualified la'e of pro [di'u]"},
+		{text: "mi .e do klama", want: "connected e (pro [mi], pro [do])"},
+		{text: "ge mi gi do klama", want: "connected ge (pro [mi], pro [do])"},
+	}
+	for _, dialect := range []string{"camxes", "ilmentufa"} {
+		for _, test := range tests {
+			text, err := convert(t, dialect, test.text)
+			if err != nil {
+				t.Errorf("%s: Convert(%q) failed: %v", dialect, test.text, err)
+				continue
+			}
+			s := firstSumti(text)
+			if s == nil {
+				t.Errorf("%s: Convert(%q) has no sumti", dialect, test.text)
+				continue
+			}
+			if got := describe(s); got != test.want {
+				t.Errorf("%s: Convert(%q) sumti=%s, want %s", dialect, test.text, got, test.want)
+			}
+			if s.Tree == nil {
+				t.Errorf("%s: Convert(%q) sumti has no Tree", dialect, test.text)
+			}
+		}
+	}
+}
+
+// firstSumti returns the sumti of the first term of the first sentence.
+func firstSumti(text *ast.Text) *ast.Sumti {
+	if len(text.Paragraphs) == 0 || len(text.Paragraphs[0].Sentences) == 0 {
+		return nil
+	}
+	s := text.Paragraphs[0].Sentences[0]
+	if len(s.Terms) == 0 {
+		return nil
+	}
+	return s.Terms[0].Sumti
+}
+
+// describe returns a description of the sumti,
+// its kind and the fields set for the kind.
+func describe(s *ast.Sumti) string {
+	var parts []string
+	add := func(ss ...string) {
+		for _, s := range ss {
+			if s != "" {
+				parts = append(parts, s)
+			}
+		}
+	}
+	switch s.Kind {
+	case ast.ProSumti:
+		add("pro", "["+strings.Join(s.Words, " ")+"]")
+	case ast.DescriptionSumti:
+		add("description", s.Quantifier, s.Article, s.InnerQuantifier)
+		if s.Possessor != nil {
+			add("of", describe(s.Possessor))
+		}
+		if s.Inner != nil {
+			add("of", describe(s.Inner))
+		}
+		if s.Selbri != nil {
+			var words []string
+			for _, u := range s.Selbri.Tanru.Units {
+				words = append(words, u.Word)
+			}
+			add("(" + strings.Join(words, " ") + ")")
+		}
+	case ast.NameSumti:
+		add("name", s.Article, "["+strings.Join(s.Names, " ")+"]")
+	case ast.QuoteSumti:
+		add("quote", s.Quote.Word, s.Quote.Delimiter)
+		if s.Quote.Text != nil {
+			add(strconv.Itoa(len(s.Quote.Text.Paragraphs)), "paragraphs")
+		} else {
+			add(`"` + s.Quote.Content + `"`)
+		}
+	case ast.NumberSumti:
+		add("number", s.Article, "["+strings.Join(s.Words, " ")+"]")
+	case ast.QualifiedSumti:
+		add("qualified", s.Article, "of", describe(s.Inner))
+	case ast.ConnectedSumti:
+		var operands []string
+		for _, o := range s.Sumti {
+			operands = append(operands, describe(o))
+		}
+		add("connected", s.Connective.Word, "("+strings.Join(operands, ", ")+")")
+	}
+	for _, r := range s.RelativeClauses {
+		add(r.Word)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestUnsupported(t *testing.T) {
+	tests := []struct {
+		dialect string
+		text    string
+		want    string
+	}{
+		{dialect: "camxes", text: ".djan. mi klama", want: `ast: unsupported CMEVLA_clause "djan"`},
+		{dialect: "camxes", text: "nai mi klama", want: `ast: unsupported NAI_clause "nai"`},
+		{dialect: "camxes", text: ".i je mi klama", want: `ast: unsupported jek "je"`},
+		{dialect: "ilmentufa", text: ".djan. mi klama", want: `ast: unsupported CMEVLA_clause "djan"`},
+	}
+	for _, test := range tests {
+		text, err := convert(t, test.dialect, test.text)
+		uerr, ok := err.(*ast.UnsupportedError)
+		if !ok {
+			t.Errorf("%s: Convert(%q) error=%v, want an *UnsupportedError", test.dialect, test.text, err)
+			continue
+		}
+		if uerr.Error() != test.want {
+			t.Errorf("%s: Convert(%q) error=%q, want %q", test.dialect, test.text, uerr.Error(), test.want)
+		}
+		// The Text converted so far is returned with the error.
+		if text == nil || len(text.Paragraphs) != 1 {
+			t.Errorf("%s: Convert(%q) returned no paragraph with its error", test.dialect, test.text)
+		}
+	}
+}
+
+func TestConvertSupported(t *testing.T) {
+	for _, text := range []string{
+		"mi klama",
+		".i mi klama",
+		"ni'o mi klama ni'o do klama",
+		"mi klama fa'o",
+		"doi .djan. mi klama",
+		"mi klama .i je do klama",
+	} {
+		if _, err := convert(t, "camxes", text); err != nil {
+			t.Errorf("Convert(%q) failed: %v", text, err)
+		}
+	}
+}
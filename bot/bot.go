@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"flag"
 	"fmt"
+	"log"
 	"regexp"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"context"
@@ -13,6 +15,7 @@ import (
 	prettyprint "github.com/eaburns/pretty"
 	"github.com/velour/chat"
 	"github.com/velour/chat/irc"
+	"within.website/johaus/dictionary"
 	"within.website/johaus/parser"
 	"within.website/johaus/pretty"
 
@@ -21,14 +24,24 @@ import (
 )
 
 var (
-	nick    = flag.String("n", "", "The bot's IRC nickname")
-	pass    = flag.String("p", "", "The bot's IRC password")
-	server  = flag.String("s", "irc.freenode.net:6697", "The IRC server")
-	channel = flag.String("c", "#velour-test", "The IRC channel")
+	nick     = flag.String("n", "", "The bot's IRC nickname")
+	pass     = flag.String("p", "", "The bot's IRC password")
+	server   = flag.String("s", "irc.freenode.net:6697", "The IRC server")
+	channel  = flag.String("c", "#velour-test", "The IRC channel")
+	dictPath = flag.String("dict", "", "The jbovlaste XML export to look up words in")
 )
 
+// dict is the dictionary loaded from the -dict flag, or nil if there is none.
+var dict *dictionary.Dictionary
+
 func main() {
 	flag.Parse()
+	if *dictPath != "" {
+		var err error
+		if dict, err = dictionary.LoadFile(*dictPath); err != nil {
+			panic(err)
+		}
+	}
 
 	ctx := context.Background()
 	cl, err := irc.DialSSL(ctx, *server, *nick, *nick, *pass, false)
@@ -73,7 +86,14 @@ func run(ctx context.Context, ch chat.Channel) error {
 			continue
 		}
 		if isParseRequest(text) {
-			parseText(ctx, msg)
+			if err := parseText(ctx, msg); err != nil {
+				log.Printf("failed to reply to a parse request: %v", err)
+			}
+		}
+		if isDefineRequest(text) {
+			if err := defineWord(ctx, msg); err != nil {
+				log.Printf("failed to reply to a define request: %v", err)
+			}
 		}
 	}
 }
@@ -117,13 +137,20 @@ func parseText(ctx context.Context, msg chat.Message) error {
 	const (
 		maxReplyBytes = 450
 		tooBigMsg     = ".u'u dukse lo ka clani"
+		parseTimeout  = 5 * time.Second
+		tooSlowMsg    = ".u'u dukse lo ka temci"
 	)
 	// If the given text is greater than the max reply bytes
 	// then all is hopeless, so give up now.
 	if len(text) > maxReplyBytes {
 		return send(ctx, ch, tooBigMsg)
 	}
-	tree, err := parser.Parse(dialect, text)
+	parseCtx, cancel := context.WithTimeout(ctx, parseTimeout)
+	defer cancel()
+	tree, err := parser.ParseContext(parseCtx, dialect, text, parser.Options{MaxBytes: maxReplyBytes})
+	if err == context.DeadlineExceeded {
+		return send(ctx, ch, tooSlowMsg)
+	}
 	if err != nil {
 		parseErr, ok := err.(*parser.Error)
 		if !ok {
@@ -148,6 +175,39 @@ func parseText(ctx context.Context, msg chat.Message) error {
 	return send(ctx, ch, reply)
 }
 
+const defineRequestPrefix = ".valsi "
+
+func isDefineRequest(text string) bool {
+	return dict != nil && strings.HasPrefix(text, defineRequestPrefix)
+}
+
+// defineWord replies with the dictionary entry of the word
+// after the define request prefix,
+// or of the word that it is a rafsi of.
+func defineWord(ctx context.Context, msg chat.Message) error {
+	const (
+		maxReplyBytes = 450
+		notFoundMsg   = "na facki"
+	)
+	word := strings.TrimSpace(strings.TrimSpace(msg.Text)[len(defineRequestPrefix):])
+	e, ok := dict.Word(word)
+	if !ok {
+		e, ok = dict.Rafsi(word)
+	}
+	if !ok {
+		return send(ctx, msg.Origin(), notFoundMsg)
+	}
+	reply := e.String()
+	if len(reply) > maxReplyBytes {
+		i := maxReplyBytes
+		for i > 0 && !utf8.RuneStart(reply[i]) {
+			i--
+		}
+		reply = reply[:i] + "…"
+	}
+	return send(ctx, msg.Origin(), reply)
+}
+
 var knownDialects = func() map[string]bool {
 	ds := make(map[string]bool)
 	for _, d := range parser.Dialects() {
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"within.website/johaus/dictionary"
+	"within.website/johaus/parser/morph"
+)
+
+// defineMain runs the define command,
+// which looks up words in a jbovlaste XML export:
+//
+//	johaus define [flags] [query ...]
+//
+// Each query is looked up as selected by the -by flag,
+// and each entry found is printed on a line.
+// Without queries, define looks up each Lojban word of the standard input.
+func defineMain(args []string) {
+	fs := flag.NewFlagSet("define", flag.ExitOnError)
+	dialect := fs.String("dialect", "camxes", "the dialect splitting the standard input into words, one of: "+dialectString)
+	dictPath := fs.String("dict", os.Getenv("JOHAUS_DICT"), "the path of the jbovlaste XML export, by default $JOHAUS_DICT")
+	by := fs.String("by", "word", "what to look up, one of: word, rafsi, gloss, selmaho")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: johaus define [flags] [query ...]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	lookup, ok := lookups[*by]
+	if !ok {
+		os.Stderr.WriteString("unknown lookup: " + *by + "\n")
+		os.Exit(2)
+	}
+	if *dictPath == "" {
+		os.Stderr.WriteString("no dictionary: use -dict or set $JOHAUS_DICT\n")
+		os.Exit(2)
+	}
+	dict, err := dictionary.LoadFile(*dictPath)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(2)
+	}
+
+	queries := fs.Args()
+	if len(queries) == 0 {
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+		words, err := morph.Words(*dialect, string(data))
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+		for _, w := range words {
+			if w.Class != morph.NonLojban && w.Class != morph.Foreign {
+				queries = append(queries, w.Text)
+			}
+		}
+	}
+	var missing bool
+	for _, q := range queries {
+		es := lookup(dict, q)
+		if len(es) == 0 {
+			os.Stderr.WriteString(q + ": not found\n")
+			missing = true
+		}
+		for _, e := range es {
+			fmt.Println(e)
+		}
+	}
+	if missing {
+		os.Exit(1)
+	}
+}
+
+// lookups are the dictionary lookups of the define command, by the value of its -by flag.
+var lookups = map[string]func(*dictionary.Dictionary, string) []*dictionary.Entry{
+	"word":    func(d *dictionary.Dictionary, q string) []*dictionary.Entry { return one(d.Word(q)) },
+	"rafsi":   func(d *dictionary.Dictionary, q string) []*dictionary.Entry { return one(d.Rafsi(q)) },
+	"gloss":   (*dictionary.Dictionary).Gloss,
+	"selmaho": (*dictionary.Dictionary).Selmaho,
+}
+
+func one(e *dictionary.Entry, ok bool) []*dictionary.Entry {
+	if !ok {
+		return nil
+	}
+	return []*dictionary.Entry{e}
+}
@@ -0,0 +1,342 @@
+// Package dictionary is an in-memory Lojban dictionary
+// loaded from an XML export of the jbovlaste dictionary,
+// such as https://jbovlaste.lojban.org/export/xml-export.html?lang=en.
+//
+// An export lists the Lojban words, each a valsi element:
+//
+//	<valsi word="klama" type="gismu">
+//		<rafsi>kla</rafsi>
+//		<definition>$x_{1}$ comes/goes to destination $x_{2}$ …</definition>
+//		<notes>…</notes>
+//		<glossword word="come" />
+//		<keyword word="comer" place="1" />
+//	</valsi>
+//
+// Cmavo have a selmaho element with their selma'o.
+// An export also lists the words of its natural language, each an nlword element
+// giving the Lojban word it glosses, and the place it glosses, if any:
+//
+//	<nlword word="comer" valsi="klama" place="1" />
+package dictionary
+
+import (
+	"encoding/xml"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"within.website/johaus/parser/morph"
+)
+
+// An Entry is the dictionary entry of a Lojban word.
+type Entry struct {
+	// Word is the word, written with ' and not h.
+	Word string
+
+	// Type is the type of the word in jbovlaste, such as
+	// gismu, cmavo, lujvo, fu'ivla, cmevla, cmavo-compound,
+	// or experimental gismu.
+	Type string
+
+	// Selmaho is the selma'o of a cmavo, as in jbovlaste,
+	// such as KOhA or UI1, or the empty string.
+	Selmaho string
+
+	// Rafsi are the rafsi of the word, not including the four-letter rafsi of a gismu.
+	Rafsi []string
+
+	// Definition is the definition of the word,
+	// with places written as in jbovlaste, such as $x_{1}$.
+	Definition string
+
+	// Notes are the notes on the word, or the empty string.
+	Notes string
+
+	// Glosses are the natural-language words that gloss the word, such as come.
+	Glosses []string
+
+	// Places are the keywords of the places of the word, such as comer,
+	// in order from x1 to the last place in the definition,
+	// so there is one for each place.
+	// A place with no keyword is the empty string.
+	Places []string
+}
+
+// String returns a one-line description of the entry, such as
+//
+//	klama [gismu] rafsi kla: x1 comes/goes to destination x2 …
+func (e *Entry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Word)
+	b.WriteString(" [")
+	if e.Selmaho != "" {
+		b.WriteString(e.Selmaho + " ")
+	}
+	b.WriteString(e.Type + "]")
+	if len(e.Rafsi) > 0 {
+		b.WriteString(" rafsi " + strings.Join(e.Rafsi, " "))
+	}
+	b.WriteString(": ")
+	b.WriteString(Plain(e.Definition))
+	return b.String()
+}
+
+// placeVar matches the places of a jbovlaste definition,
+// such as $x_1$, $x_{1}$, or x_1 within $x_1=x_2$.
+var placeVar = regexp.MustCompile(`([a-z])_\{?([0-9]+)\}?`)
+
+// Plain returns the text of a jbovlaste definition or note
+// with its LaTeX math written as plain text, such as x1 for $x_{1}$.
+func Plain(s string) string {
+	s = placeVar.ReplaceAllString(s, "$1$2")
+	return strings.Replace(s, "$", "", -1)
+}
+
+// A Dictionary is a set of entries indexed by word, rafsi, gloss, and selma'o.
+// A Dictionary is safe for concurrent use.
+type Dictionary struct {
+	entries    []*Entry
+	words      map[string]*Entry
+	glosses    map[string][]*Entry
+	selmaho    map[string][]*Entry
+	rafsiTable *morph.RafsiTable
+}
+
+// LoadFile loads a dictionary from a jbovlaste XML export file.
+func LoadFile(path string) (*Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load loads a dictionary from a jbovlaste XML export.
+func Load(r io.Reader) (*Dictionary, error) {
+	d := &Dictionary{
+		words:   make(map[string]*Entry),
+		glosses: make(map[string][]*Entry),
+		selmaho: make(map[string][]*Entry),
+	}
+	var nlwords []xmlNLWord
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "valsi":
+			var v xmlValsi
+			if err := dec.DecodeElement(&v, &start); err != nil {
+				return nil, err
+			}
+			d.add(v)
+		case "nlword":
+			var w xmlNLWord
+			if err := dec.DecodeElement(&w, &start); err != nil {
+				return nil, err
+			}
+			nlwords = append(nlwords, w)
+		}
+	}
+	// The natural-language words may come before or after the Lojban words.
+	for _, w := range nlwords {
+		e, ok := d.words[morph.Normalize(w.Valsi)]
+		if !ok {
+			continue
+		}
+		if w.Place > 0 {
+			e.setPlace(w.Place, w.Word)
+		}
+		d.addGloss(w.Word, e)
+	}
+	sort.Slice(d.entries, func(i, j int) bool { return d.entries[i].Word < d.entries[j].Word })
+	// A rafsi added for more than one word stands for the last,
+	// so the words are added in reverse order of preference.
+	byRank := append([]*Entry(nil), d.entries...)
+	sort.SliceStable(byRank, func(i, j int) bool { return rafsiRank(byRank[i]) < rafsiRank(byRank[j]) })
+	d.rafsiTable = morph.NewRafsiTable()
+	for i := len(byRank) - 1; i >= 0; i-- {
+		if e := byRank[i]; len(e.Rafsi) > 0 || isGismu(e) {
+			d.rafsiTable.Add(e.Word, e.Rafsi...)
+		}
+	}
+	return d, nil
+}
+
+// rafsiRank ranks the entries by which a rafsi given to more than one word stands for,
+// lowest first: the official gismu and cmavo, whose rafsi are assigned,
+// and then the experimental words and all others.
+func rafsiRank(e *Entry) int {
+	if e.Type == "gismu" || e.Type == "cmavo" {
+		return 0
+	}
+	return 1
+}
+
+type xmlValsi struct {
+	Word       string   `xml:"word,attr"`
+	Type       string   `xml:"type,attr"`
+	Selmaho    string   `xml:"selmaho"`
+	Rafsi      []string `xml:"rafsi"`
+	Definition string   `xml:"definition"`
+	Notes      string   `xml:"notes"`
+	Glosswords []struct {
+		Word string `xml:"word,attr"`
+	} `xml:"glossword"`
+	Keywords []struct {
+		Word  string `xml:"word,attr"`
+		Place int    `xml:"place,attr"`
+	} `xml:"keyword"`
+}
+
+type xmlNLWord struct {
+	Word  string `xml:"word,attr"`
+	Valsi string `xml:"valsi,attr"`
+	Place int    `xml:"place,attr"`
+}
+
+// add adds the entry of a valsi element.
+func (d *Dictionary) add(v xmlValsi) {
+	e := &Entry{
+		Word:       morph.Normalize(v.Word),
+		Type:       v.Type,
+		Selmaho:    strings.TrimSpace(v.Selmaho),
+		Definition: strings.TrimSpace(v.Definition),
+		Notes:      strings.TrimSpace(v.Notes),
+	}
+	if e.Word == "" {
+		return
+	}
+	for _, r := range v.Rafsi {
+		if r = morph.Normalize(r); r != "" {
+			e.Rafsi = append(e.Rafsi, r)
+		}
+	}
+	for _, m := range placeVar.FindAllStringSubmatch(e.Definition, -1) {
+		if m[1] != "x" {
+			continue
+		}
+		if n, err := strconv.Atoi(m[2]); err == nil {
+			e.setPlace(n, "")
+		}
+	}
+	if _, ok := d.words[e.Word]; ok {
+		// A word is in the export once; keep the first if not.
+		return
+	}
+	d.entries = append(d.entries, e)
+	d.words[e.Word] = e
+	if e.Selmaho != "" {
+		s := selmahoClass(e.Selmaho)
+		d.selmaho[s] = append(d.selmaho[s], e)
+	}
+	for _, g := range v.Glosswords {
+		e.Glosses = append(e.Glosses, g.Word)
+		d.addGloss(g.Word, e)
+	}
+	for _, k := range v.Keywords {
+		if k.Place > 0 {
+			e.setPlace(k.Place, k.Word)
+		}
+		d.addGloss(k.Word, e)
+	}
+}
+
+// setPlace sets the keyword of place n of the entry, such as comer for x1 of klama,
+// adding places up to n if it has fewer.
+// The empty keyword adds the places but does not change a keyword.
+func (e *Entry) setPlace(n int, keyword string) {
+	for len(e.Places) < n {
+		e.Places = append(e.Places, "")
+	}
+	if keyword != "" {
+		e.Places[n-1] = keyword
+	}
+}
+
+// addGloss indexes the entry by the gloss, once.
+func (d *Dictionary) addGloss(gloss string, e *Entry) {
+	g := strings.ToLower(strings.TrimSpace(gloss))
+	if g == "" {
+		return
+	}
+	for _, f := range d.glosses[g] {
+		if f == e {
+			return
+		}
+	}
+	d.glosses[g] = append(d.glosses[g], e)
+}
+
+func isGismu(e *Entry) bool {
+	return strings.HasSuffix(e.Type, "gismu") && len(e.Word) == 5
+}
+
+// selmahoClass returns the selma'o of the selma'o as written in jbovlaste,
+// without the subclass, such as UI for UI1 or UI3a, or BAI for BAI*.
+func selmahoClass(s string) string {
+	return strings.TrimRight(s, "0123456789*abcdefgijklmnopqrstuvwxyz")
+}
+
+// Len returns the number of entries in the dictionary.
+func (d *Dictionary) Len() int { return len(d.entries) }
+
+// Entries returns the entries of the dictionary, sorted by word.
+// The slice must not be modified.
+func (d *Dictionary) Entries() []*Entry { return d.entries }
+
+// Word returns the entry of the word, which may be written with h for '
+// and in any case, and whether it is in the dictionary.
+func (d *Dictionary) Word(word string) (*Entry, bool) {
+	e, ok := d.words[morph.Normalize(word)]
+	return e, ok
+}
+
+// Rafsi returns the entry of the word that the rafsi stands for,
+// including the four-letter rafsi of gismu,
+// and whether it is in the dictionary.
+// A rafsi given to more than one word in the dictionary
+// stands for an official gismu or cmavo, if one of them is,
+// and otherwise for the first of them in alphabetical order,
+// as it does in the RafsiTable.
+func (d *Dictionary) Rafsi(rafsi string) (*Entry, bool) {
+	w, ok := d.rafsiTable.Word(morph.Normalize(rafsi))
+	if !ok {
+		return nil, false
+	}
+	return d.Word(w)
+}
+
+// Gloss returns the entries of the words glossed by the natural-language word,
+// in any case, including the keywords of their places.
+func (d *Dictionary) Gloss(gloss string) []*Entry {
+	return d.glosses[strings.ToLower(strings.TrimSpace(gloss))]
+}
+
+// Selmaho returns the entries of the cmavo of the selma'o,
+// such as KOhA, including those of all of its subclasses,
+// such as UI1 and UI3a for UI.
+// The selma'o is written as in the grammar, with h for ',
+// and a subclass, such as UI1, is ignored.
+func (d *Dictionary) Selmaho(selmaho string) []*Entry {
+	return d.selmaho[selmahoClass(strings.TrimSpace(selmaho))]
+}
+
+// RafsiTable returns a morph.RafsiTable of the rafsi of all words in the dictionary,
+// for use with its Jvokaha and Jvozba methods.
+// A rafsi given to more than one word stands for the same word as for the Rafsi method.
+// The table must not be modified.
+func (d *Dictionary) RafsiTable() *morph.RafsiTable { return d.rafsiTable }
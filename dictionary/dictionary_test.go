@@ -0,0 +1,243 @@
+package dictionary_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"within.website/johaus/dictionary"
+)
+
+const testXML = `<?xml version="1.0" encoding="UTF-8"?>
+<dictionary>
+<direction from="English" to="lojban">
+<nlword word="comer" valsi="klama" place="1" />
+<nlword word="destination" valsi="klama" place="2" />
+<nlword word="Come" valsi="klama" />
+<nlword word="me" valsi="mi" />
+<nlword word="lost" valsi="nosuchword" />
+</direction>
+<direction from="lojban" to="English">
+<valsi word="klama" type="gismu">
+<rafsi>kla</rafsi>
+<definition>$x_{1}$ comes/goes to destination $x_{2}$ from origin $x_{3}$ via route $x_{4}$ using means/vehicle $x_{5}$.</definition>
+<notes> Also travels, journeys. </notes>
+<glossword word="come" />
+<glossword word="go" />
+<keyword word="route" place="4" />
+</valsi>
+<valsi word="bakla" type="experimental gismu">
+<rafsi>kla</rafsi>
+<rafsi>bak</rafsi>
+<definition>$x_1$ is an experiment.</definition>
+</valsi>
+<valsi word="zbaku" type="experimental gismu">
+<rafsi>bak</rafsi>
+<definition>$x_1$ is another experiment.</definition>
+</valsi>
+<valsi word="brivla" type="lujvo">
+<definition>$b_1=v_1$ is a predicate word.</definition>
+<glossword word="predicate" />
+</valsi>
+<valsi word="mi" type="cmavo">
+<selmaho>KOhA3</selmaho>
+<definition>pro-sumti: me; the speaker.</definition>
+<glossword word="I" />
+</valsi>
+<valsi word="ko'a" type="cmavo">
+<selmaho>KOhA4</selmaho>
+<definition>it-1; 1st assignable pro-sumti.</definition>
+</valsi>
+<valsi word=".uinai" type="cmavo-compound">
+<definition>attitudinal: unhappiness.</definition>
+</valsi>
+<valsi word=".ui" type="cmavo">
+<selmaho>UI1</selmaho>
+<definition>attitudinal: happiness - unhappiness.</definition>
+<glossword word="happiness" />
+</valsi>
+<valsi word="cmavo" type="gismu">
+<rafsi>ma'o</rafsi>
+<definition>$x_1$ is a structure word of grammatical class $x_2$.</definition>
+</valsi>
+</direction>
+</dictionary>
+`
+
+func load(t *testing.T) *dictionary.Dictionary {
+	t.Helper()
+	d, err := dictionary.Load(strings.NewReader(testXML))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return d
+}
+
+func TestLoad(t *testing.T) {
+	d := load(t)
+	var words []string
+	for _, e := range d.Entries() {
+		words = append(words, e.Word)
+	}
+	want := []string{"bakla", "brivla", "cmavo", "klama", "ko'a", "mi", "ui", "uinai", "zbaku"}
+	if d.Len() != len(want) || !reflect.DeepEqual(words, want) {
+		t.Errorf("Entries()=%q, Len()=%d, want %q", words, d.Len(), want)
+	}
+
+	e, ok := d.Word("klama")
+	if !ok {
+		t.Fatalf("Word(klama) is not in the dictionary")
+	}
+	wantEntry := &dictionary.Entry{
+		Word:       "klama",
+		Type:       "gismu",
+		Rafsi:      []string{"kla"},
+		Definition: "$x_{1}$ comes/goes to destination $x_{2}$ from origin $x_{3}$ via route $x_{4}$ using means/vehicle $x_{5}$.",
+		Notes:      "Also travels, journeys.",
+		Glosses:    []string{"come", "go"},
+		Places:     []string{"comer", "destination", "", "route", ""},
+	}
+	if !reflect.DeepEqual(e, wantEntry) {
+		t.Errorf("Word(klama)=%+v, want %+v", e, wantEntry)
+	}
+	const wantString = "klama [gismu] rafsi kla: x1 comes/goes to destination x2 from origin x3 via route x4 using means/vehicle x5."
+	if s := e.String(); s != wantString {
+		t.Errorf("Word(klama).String()=%q, want %q", s, wantString)
+	}
+	if e, _ := d.Word("mi"); e.String() != "mi [KOhA3 cmavo]: pro-sumti: me; the speaker." {
+		t.Errorf("Word(mi).String()=%q", e.String())
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	if _, err := dictionary.Load(strings.NewReader(`<dictionary><valsi word="klama">`)); err == nil {
+		t.Errorf("Load of a truncated export succeeded, want an error")
+	}
+}
+
+func TestWord(t *testing.T) {
+	d := load(t)
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "klama", want: "klama"},
+		{word: "KLAMA", want: "klama"},
+		{word: "kohA", want: "ko'a"},
+		{word: ".ui", want: "ui"},
+		{word: "ui", want: "ui"},
+		{word: "nosuchword", want: ""},
+	}
+	for _, test := range tests {
+		e, ok := d.Word(test.word)
+		switch {
+		case test.want == "" && ok:
+			t.Errorf("Word(%q)=%s, want none", test.word, e.Word)
+		case test.want != "" && (!ok || e.Word != test.want):
+			t.Errorf("Word(%q)=%v, %v, want %s", test.word, e, ok, test.want)
+		}
+	}
+}
+
+func TestRafsi(t *testing.T) {
+	d := load(t)
+	tests := []struct {
+		rafsi string
+		want  string
+	}{
+		{rafsi: "val", want: ""},
+		// The four-letter rafsi of a gismu.
+		{rafsi: "klam", want: "klama"},
+		{rafsi: "ma'o", want: "cmavo"},
+		{rafsi: "mahO", want: "cmavo"},
+		// An official gismu has its rafsi,
+		// though an experimental gismu before it alphabetically has the same rafsi.
+		{rafsi: "kla", want: "klama"},
+		// Otherwise, the first word alphabetically has the rafsi.
+		{rafsi: "bak", want: "bakla"},
+		{rafsi: "bakl", want: "bakla"},
+	}
+	for _, test := range tests {
+		e, ok := d.Rafsi(test.rafsi)
+		switch {
+		case test.want == "" && ok:
+			t.Errorf("Rafsi(%q)=%s, want none", test.rafsi, e.Word)
+		case test.want != "" && (!ok || e.Word != test.want):
+			t.Errorf("Rafsi(%q)=%v, %v, want %s", test.rafsi, e, ok, test.want)
+		}
+	}
+
+	table := d.RafsiTable()
+	if w, _ := table.Word("kla"); w != "klama" {
+		t.Errorf("RafsiTable().Word(kla)=%q, want klama", w)
+	}
+	if r := table.Rafsi("bakla"); !reflect.DeepEqual(r, []string{"bak", "bakl"}) {
+		t.Errorf("RafsiTable().Rafsi(bakla)=%q, want [bak bakl]", r)
+	}
+}
+
+func TestGloss(t *testing.T) {
+	d := load(t)
+	tests := []struct {
+		gloss string
+		want  []string
+	}{
+		// Glosses, keywords, and natural-language words,
+		// each giving the word once.
+		{gloss: "come", want: []string{"klama"}},
+		{gloss: "Comer", want: []string{"klama"}},
+		{gloss: "route", want: []string{"klama"}},
+		{gloss: "destination", want: []string{"klama"}},
+		{gloss: "me", want: []string{"mi"}},
+		{gloss: "predicate", want: []string{"brivla"}},
+		{gloss: "lost", want: nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, e := range d.Gloss(test.gloss) {
+			got = append(got, e.Word)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Gloss(%q)=%q, want %q", test.gloss, got, test.want)
+		}
+	}
+}
+
+func TestSelmaho(t *testing.T) {
+	d := load(t)
+	tests := []struct {
+		selmaho string
+		want    []string
+	}{
+		{selmaho: "KOhA", want: []string{"mi", "ko'a"}},
+		{selmaho: "KOhA3", want: []string{"mi", "ko'a"}},
+		{selmaho: "UI", want: []string{"ui"}},
+		{selmaho: "BAI", want: nil},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, e := range d.Selmaho(test.selmaho) {
+			got = append(got, e.Word)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Selmaho(%q)=%q, want %q", test.selmaho, got, test.want)
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := []struct {
+		def  string
+		want string
+	}{
+		{def: "$x_{1}$ comes to $x_{2}$", want: "x1 comes to x2"},
+		{def: "$x_1$ is a word", want: "x1 is a word"},
+		{def: "$b_1=v_1$ is a predicate word", want: "b1=v1 is a predicate word"},
+		{def: "no places", want: "no places"},
+	}
+	for _, test := range tests {
+		if got := dictionary.Plain(test.def); got != test.want {
+			t.Errorf("Plain(%q)=%q, want %q", test.def, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"within.website/johaus/parser"
+)
+
+// fmtMain runs the fmt command, which reformats Lojban texts like gofmt:
+//
+//	johaus fmt [flags] [path ...]
+//
+// Each sentence begins on a new line, and each paragraph after a blank line.
+// Words are lowercase, written with ' for h, and separated by a single space,
+// with pauses before words beginning with a vowel and around cmevla.
+// The text of zoi and la'o quotations is kept as it was.
+// A text that does not parse is left as it was and its error is reported.
+//
+// Without paths, fmt reformats the standard input.
+func fmtMain(args []string) {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	dialect := fs.String("dialect", "camxes", "the dialect, one of: "+dialectString)
+	showDiff := fs.Bool("d", false, "display diffs instead of rewriting files")
+	list := fs.Bool("l", false, "list files whose formatting differs")
+	write := fs.Bool("w", false, "write the result to the file instead of the standard output")
+	terminators := fs.String("terminators", "keep", "the terminators to write, one of: keep, elide (as few as possible), explicit (all)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: johaus fmt [flags] [path ...]\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	switch *terminators {
+	case "keep", "elide", "explicit":
+	default:
+		os.Stderr.WriteString("unknown terminators policy: " + *terminators + "\n")
+		os.Exit(2)
+	}
+	f := formatter{
+		dialect:     *dialect,
+		terminators: *terminators,
+		diff:        *showDiff,
+		list:        *list,
+		write:       *write,
+	}
+
+	if fs.NArg() == 0 {
+		if *write {
+			os.Stderr.WriteString("cannot use -w with standard input\n")
+			os.Exit(2)
+		}
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(2)
+		}
+		f.format("<standard input>", data)
+	}
+	for _, path := range fs.Args() {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			f.report(err)
+			continue
+		}
+		f.format(path, data)
+	}
+	if f.failed {
+		os.Exit(2)
+	}
+}
+
+type formatter struct {
+	dialect     string
+	terminators string
+	diff        bool
+	list        bool
+	write       bool
+
+	// failed is whether there was an error.
+	failed bool
+}
+
+// format reformats the text of a file and writes it as selected by the flags.
+func (f *formatter) format(path string, data []byte) {
+	res, err := f.reformat(path, data)
+	if err != nil {
+		f.report(err)
+		return
+	}
+	if !bytes.Equal(data, res) {
+		f.changed(path, data, res)
+	}
+	if !f.list && !f.diff && !f.write {
+		os.Stdout.Write(res)
+	}
+}
+
+// reformat returns the reformatted text of a file.
+func (f *formatter) reformat(path string, data []byte) ([]byte, error) {
+	tree, err := parser.Parse(f.dialect, string(data))
+	if err != nil {
+		if err, ok := err.(*parser.Error); ok {
+			err.FilePath = path
+		}
+		return nil, err
+	}
+	switch f.terminators {
+	case "elide":
+		if err := parser.ElideTerminators(f.dialect, tree); err != nil {
+			return nil, err
+		}
+	case "explicit":
+		parser.AddElidedTerminators(tree)
+	}
+	text, err := parser.UnparseOptions{Lines: true, Normalize: true}.Format(f.dialect, tree)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(text + "\n"), nil
+}
+
+// changed lists, writes, or diffs the reformatted text of a file
+// that differs from its text, as selected by the flags.
+func (f *formatter) changed(path string, data, res []byte) {
+	if f.list {
+		fmt.Println(path)
+	}
+	if f.write {
+		info, err := os.Stat(path)
+		if err != nil {
+			f.report(err)
+			return
+		}
+		if err := ioutil.WriteFile(path, res, info.Mode().Perm()); err != nil {
+			f.report(err)
+			return
+		}
+	}
+	if f.diff {
+		d, err := diff(path, data, res)
+		if err != nil {
+			f.report(fmt.Errorf("computing diff: %s", err))
+			return
+		}
+		fmt.Printf("diff -u %s.orig %s\n", path, path)
+		os.Stdout.Write(d)
+	}
+}
+
+func (f *formatter) report(err error) {
+	os.Stderr.WriteString(err.Error() + "\n")
+	f.failed = true
+}
+
+// diff returns the unified diff of a and b using the diff command.
+func diff(path string, a, b []byte) ([]byte, error) {
+	fa, err := writeTemp(a)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fa)
+	fb, err := writeTemp(b)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(fb)
+
+	data, err := exec.Command("diff", "-u", "--label", path+".orig", "--label", path, fa, fb).CombinedOutput()
+	if len(data) > 0 {
+		// diff exits with a non-zero status when the files differ.
+		return data, nil
+	}
+	return nil, err
+}
+
+func writeTemp(data []byte) (string, error) {
+	f, err := ioutil.TempFile("", "johaus")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import "testing"
+
+func TestReformat(t *testing.T) {
+	tests := []struct {
+		text        string
+		terminators string
+		want        string
+	}{
+		{
+			text:        "coi ro do .i mi klama lo zarci ku ni'o la .alis. cu citka",
+			terminators: "keep",
+			want:        "coi ro do\n.i mi klama lo zarci ku\n\nni'o la .alis. cu citka\n",
+		},
+		{
+			text:        "mi klama lo zarci ku",
+			terminators: "elide",
+			want:        "mi klama lo zarci\n",
+		},
+		{
+			text:        "mi klama",
+			terminators: "explicit",
+			want:        "mi cu klama vau\n",
+		},
+		// The text of quotations is kept, with its pause characters.
+		{
+			text:        "mi cusku zoi gy ?! gy .i do cusku zoi gy .i gy",
+			terminators: "keep",
+			want:        "mi cusku zoi gy. ?! .gy\n.i do cusku zoi gy. .i .gy\n",
+		},
+		{
+			text:        "mi cusku zoi gy. Hello, World! gy. .i la'o dy. .NET. dy. cmene",
+			terminators: "keep",
+			want:        "mi cusku zoi gy. Hello, World! .gy\n.i la'o dy. .NET. .dy cmene\n",
+		},
+		{
+			text:        "mi cusku zoi gy ?! gy vau",
+			terminators: "elide",
+			want:        "mi cusku zoi gy. ?! .gy\n",
+		},
+		{
+			text:        "mi cusku zoi gy ?! gy",
+			terminators: "explicit",
+			want:        "mi cu cusku zoi gy. ?! .gy vau\n",
+		},
+	}
+	for _, test := range tests {
+		f := formatter{dialect: "camxes", terminators: test.terminators}
+		got, err := f.reformat("test.jbo", []byte(test.text))
+		if err != nil || string(got) != test.want {
+			t.Errorf("reformat(%q) with -terminators=%s=%q, %v, want %q",
+				test.text, test.terminators, got, err, test.want)
+			continue
+		}
+		// Reformatting is idempotent.
+		if again, err := f.reformat("test.jbo", got); err != nil || string(again) != test.want {
+			t.Errorf("reformat(%q) with -terminators=%s=%q, %v, want %q",
+				got, test.terminators, again, err, test.want)
+		}
+	}
+}
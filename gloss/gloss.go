@@ -0,0 +1,164 @@
+// Package gloss builds interlinear glosses of parsed Lojban text:
+// each word with its selma'o and a natural-language word from a dictionary,
+// grouped into the sumti and selbri of the parse tree.
+//
+// The glosses are printed by pretty.Interlinear and pretty.InterlinearHTML.
+package gloss
+
+import (
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/dictionary"
+	"within.website/johaus/parser"
+)
+
+// A Sentence is the gloss of a sentence:
+// the groups of its words, in order.
+// A sentence begins at each I or NIhO word.
+type Sentence struct {
+	Groups []Group
+}
+
+// A Group is a sumti, a selbri, or a word outside of any sumti and selbri,
+// such as the cu before a selbri.
+type Group struct {
+	// Kind is sumti, selbri, or the empty string for a word
+	// outside of any sumti and selbri.
+	Kind string
+
+	// Words are the words of the group.
+	Words []Word
+}
+
+// A Word is a glossed word.
+type Word struct {
+	// Text is the word as written.
+	Text string
+
+	// Selmaho is the selma'o of a cmavo, such as KOhA,
+	// or the grammar rule of another word:
+	// BRIVLA, CMEVLA, or zoi_word for the text of a zoi quotation.
+	Selmaho string
+
+	// Gloss is the gloss of the word, such as go for klama,
+	// or the empty string if it has none.
+	Gloss string
+}
+
+// Gloss returns the glosses of the sentences of a parse tree of the dialect,
+// with the words glossed from the dictionary, which may be nil.
+//
+// The tree must not be simplified by CollapseLists,
+// which removes the sumti and selbri nodes that group the words;
+// the other simplifications, such as parser.RemoveMorphology, do not change the gloss.
+// Elided terminators are not glossed, even if added by parser.AddElidedTerminators.
+func Gloss(dialect string, dict *dictionary.Dictionary, tree *peg.Node) []Sentence {
+	g := glosser{dialect: dialect, dict: dict}
+	g.node(tree)
+	g.endSentence()
+	return g.sentences
+}
+
+type glosser struct {
+	dialect   string
+	dict      *dictionary.Dictionary
+	sentences []Sentence
+	cur       Sentence
+}
+
+func (g *glosser) endSentence() {
+	if len(g.cur.Groups) > 0 {
+		g.sentences = append(g.sentences, g.cur)
+		g.cur = Sentence{}
+	}
+}
+
+func (g *glosser) node(n *peg.Node) {
+	switch {
+	case parser.IsWord(n):
+		if n.Name == "I" || n.Name == "NIhO" {
+			g.endSentence()
+		}
+		if w, ok := g.word(n); ok {
+			g.cur.Groups = append(g.cur.Groups, Group{Words: []Word{w}})
+		}
+	case parser.Rule(n.Name) == "sumti" || parser.Rule(n.Name) == "selbri":
+		grp := Group{Kind: parser.Rule(n.Name)}
+		g.words(&grp, n)
+		if len(grp.Words) > 0 {
+			g.cur.Groups = append(g.cur.Groups, grp)
+		}
+	default:
+		for _, k := range n.Kids {
+			g.node(k)
+		}
+	}
+}
+
+// words adds the words beneath the node to the group.
+func (g *glosser) words(grp *Group, n *peg.Node) {
+	if parser.IsWord(n) {
+		if w, ok := g.word(n); ok {
+			grp.Words = append(grp.Words, w)
+		}
+		return
+	}
+	for _, k := range n.Kids {
+		g.words(grp, k)
+	}
+}
+
+// word returns the gloss of a word node
+// and whether it is a word written in the text.
+func (g *glosser) word(n *peg.Node) (Word, bool) {
+	text := strings.Trim(n.Text, parser.SpaceChars)
+	if text == "" {
+		return Word{}, false
+	}
+	w := Word{Text: text, Selmaho: n.Name}
+	switch n.Name {
+	case "zoi_word":
+	case "CMEVLA":
+		w.Gloss = text
+	default:
+		w.Gloss = g.gloss(text)
+	}
+	return w, true
+}
+
+// gloss returns the gloss of a Lojban word from the dictionary:
+// its first gloss word, or else the keyword of its first place.
+// A lujvo that is not in the dictionary, or has no gloss, is glossed
+// by the glosses of its rafsi, joined by -, such as predicate-word.
+func (g *glosser) gloss(word string) string {
+	if g.dict == nil {
+		return ""
+	}
+	if e, ok := g.dict.Word(word); ok && entryGloss(e) != "" {
+		return entryGloss(e)
+	}
+	rafsi, err := g.dict.RafsiTable().Jvokaha(g.dialect, word)
+	if err != nil {
+		return ""
+	}
+	var parts []string
+	for _, r := range rafsi {
+		e, ok := g.dict.Word(r.Word)
+		if !ok || entryGloss(e) == "" {
+			return ""
+		}
+		parts = append(parts, entryGloss(e))
+	}
+	return strings.Join(parts, "-")
+}
+
+func entryGloss(e *dictionary.Entry) string {
+	switch {
+	case len(e.Glosses) > 0:
+		return e.Glosses[0]
+	case len(e.Places) > 0:
+		return e.Places[0]
+	}
+	return ""
+}
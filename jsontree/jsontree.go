@@ -0,0 +1,221 @@
+// Package jsontree defines a stable JSON encoding of parse trees and parse errors.
+//
+// A parse is encoded as a Result object:
+//
+//	{
+//		"tree": Node,        // the parse tree, if the parse succeeded
+//		"spans": [Node, …],  // the trees of the spans that parsed, for a recovering parse
+//		"errors": [Error, …] // the syntax errors, if the parse failed
+//	}
+//
+// A parse tree node is encoded as a Node object:
+//
+//	{
+//		"name": "sumti",     // the grammar rule name, or "" for an anonymous node
+//		"text": "lo zarci",  // the text of the node
+//		"kids": [Node, …],   // the kids of the node, omitted if there are none
+//		"start": Loc,        // the location of the beginning of the node, optional
+//		"end": Loc,          // the location just after the end of the node, optional
+//		"elided": true       // whether the node is an elided terminator, omitted if false
+//	}
+//
+// A location is encoded as a Loc object,
+// in which lines and columns begin at 1 and offsets begin at 0:
+//
+//	{
+//		"byte": 12,          // the offset in bytes
+//		"rune": 12,          // the offset in runes
+//		"line": 1,           // the line number
+//		"column": 13,        // the column in runes
+//		"utf16": 12,         // the offset in UTF-16 code units
+//		"utf16Column": 13    // the column in UTF-16 code units
+//	}
+//
+// A syntax error is encoded as an Error object:
+//
+//	{
+//		"file": "text.jbo",  // the path of the file, omitted if there is none
+//		"location": Loc,     // the location of the error
+//		"want": ["KU", …]    // the expected words or rules
+//	}
+//
+// Fields may be added to these objects in the future,
+// but the fields above will not be changed or removed.
+package jsontree
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+)
+
+// A Result is the JSON encoding of the result of a parse.
+type Result struct {
+	// Tree is the parse tree of a successful parse.
+	Tree *Node `json:"tree,omitempty"`
+
+	// Spans are the parse trees of the spans of text that parsed
+	// during a recovering parse.
+	Spans []*Node `json:"spans,omitempty"`
+
+	// Errors are the syntax errors of a failed parse.
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// A Node is the JSON encoding of a parse tree node.
+type Node struct {
+	// Name is the grammar rule name of the node.
+	Name string `json:"name"`
+
+	// Text is the text of the node.
+	Text string `json:"text"`
+
+	// Kids are the kids of the node.
+	Kids []*Node `json:"kids,omitempty"`
+
+	// Start is the location of the beginning of the node, if known.
+	Start *Loc `json:"start,omitempty"`
+
+	// End is the location just after the end of the node, if known.
+	End *Loc `json:"end,omitempty"`
+
+	// Elided is whether the node is an elided terminator.
+	Elided bool `json:"elided,omitempty"`
+}
+
+// A Loc is the JSON encoding of a parser.Loc.
+type Loc struct {
+	Byte        int `json:"byte"`
+	Rune        int `json:"rune"`
+	Line        int `json:"line"`
+	Column      int `json:"column"`
+	UTF16       int `json:"utf16"`
+	UTF16Column int `json:"utf16Column"`
+}
+
+// An Error is the JSON encoding of a parser.Error.
+type Error struct {
+	// File is the path of the file containing the error, if any.
+	File string `json:"file,omitempty"`
+
+	// Location is the location of the error.
+	Location Loc `json:"location"`
+
+	// Want are the expected words or rules.
+	Want []string `json:"want"`
+
+	// Kind is word for the word-level error of a parser.ErrorPair,
+	// raw for its raw, morphological error,
+	// and empty for any other error.
+	Kind string `json:"kind,omitempty"`
+}
+
+// Encode writes the JSON encoding of the Result to the Writer.
+func Encode(w io.Writer, res *Result) error {
+	return json.NewEncoder(w).Encode(res)
+}
+
+// Decode reads the JSON encoding of a Result from the Reader.
+func Decode(r io.Reader) (*Result, error) {
+	var res Result
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// FromTree returns the Node encoding a parse tree.
+// If ranges is non-nil, the nodes in it are encoded with their positions.
+func FromTree(tree *peg.Node, ranges map[*peg.Node]parser.Range) *Node {
+	n := &Node{Name: tree.Name, Text: tree.Text}
+	r, ok := ranges[tree]
+	if ok {
+		start, end := FromLoc(r.Start), FromLoc(r.End)
+		n.Start, n.End = &start, &end
+	}
+	n.Elided = parser.IsElidedTerminator(tree)
+	for _, k := range tree.Kids {
+		n.Kids = append(n.Kids, FromTree(k, ranges))
+	}
+	return n
+}
+
+// Tree returns the parse tree encoded by the Node
+// and the Ranges of its nodes that have positions.
+func (n *Node) Tree() (*peg.Node, map[*peg.Node]parser.Range) {
+	ranges := make(map[*peg.Node]parser.Range)
+	return n.tree(ranges), ranges
+}
+
+func (n *Node) tree(ranges map[*peg.Node]parser.Range) *peg.Node {
+	tree := &peg.Node{Name: n.Name, Text: n.Text}
+	if n.Start != nil && n.End != nil {
+		ranges[tree] = parser.Range{Start: n.Start.Loc(), End: n.End.Loc()}
+	}
+	for _, k := range n.Kids {
+		tree.Kids = append(tree.Kids, k.tree(ranges))
+	}
+	return tree
+}
+
+// FromLoc returns the Loc encoding a parser.Loc.
+func FromLoc(loc parser.Loc) Loc {
+	return Loc{
+		Byte:        loc.Byte,
+		Rune:        loc.Rune,
+		Line:        loc.Line,
+		Column:      loc.Column,
+		UTF16:       loc.UTF16,
+		UTF16Column: loc.UTF16Column,
+	}
+}
+
+// Loc returns the parser.Loc encoded by the Loc.
+func (loc Loc) Loc() parser.Loc {
+	return parser.Loc{
+		Byte:        loc.Byte,
+		Rune:        loc.Rune,
+		Line:        loc.Line,
+		Column:      loc.Column,
+		UTF16:       loc.UTF16,
+		UTF16Column: loc.UTF16Column,
+	}
+}
+
+// FromError returns the Error encoding a parser.Error.
+func FromError(err *parser.Error) *Error {
+	return &Error{File: err.FilePath, Location: FromLoc(err.Loc), Want: err.Want}
+}
+
+// FromErrors returns the Errors encoding the syntax errors in err,
+// which may be a *parser.Error, a *parser.ErrorPair, or a parser.ErrorList of them,
+// and whether err contains only syntax errors.
+// The errors of a *parser.ErrorPair are labeled by their Kind.
+func FromErrors(err error) ([]*Error, bool) {
+	switch err := err.(type) {
+	case *parser.Error:
+		return []*Error{FromError(err)}, true
+	case *parser.ErrorPair:
+		word, raw := FromError(err.Word), FromError(err.Raw)
+		word.Kind, raw.Kind = "word", "raw"
+		return []*Error{word, raw}, true
+	case parser.ErrorList:
+		var errs []*Error
+		for _, e := range err {
+			es, ok := FromErrors(e)
+			if !ok {
+				return nil, false
+			}
+			errs = append(errs, es...)
+		}
+		return errs, true
+	}
+	return nil, false
+}
+
+// ParserError returns the parser.Error encoded by the Error.
+func (err *Error) ParserError() *parser.Error {
+	return &parser.Error{Loc: err.Location.Loc(), FilePath: err.File, Want: err.Want}
+}
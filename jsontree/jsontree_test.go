@@ -0,0 +1,161 @@
+package jsontree_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/jsontree"
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func TestTreeRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		"mi klama lo zarci",
+		"lo mlatu cu sipna .i do citka",
+		"mi cusku zoi gy. Hello, World! .gy",
+		"mi cusku zoi gy. Ĉu vi? 😀 .gy\n.i do",
+	} {
+		tree, err := parser.Parse("camxes", text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", text, err)
+			continue
+		}
+		for _, ranges := range []map[*peg.Node]parser.Range{nil, parser.Ranges(text, tree)} {
+			var b bytes.Buffer
+			if err := jsontree.Encode(&b, &jsontree.Result{Tree: jsontree.FromTree(tree, ranges)}); err != nil {
+				t.Errorf("Encode(%q) failed: %v", text, err)
+				continue
+			}
+			res, err := jsontree.Decode(&b)
+			if err != nil {
+				t.Errorf("Decode(%q) failed: %v", text, err)
+				continue
+			}
+			got, gotRanges := res.Tree.Tree()
+			checkTree(t, text, tree, got, ranges, gotRanges)
+		}
+	}
+}
+
+// checkTree checks that a decoded tree has the names, texts, and ranges of the tree.
+func checkTree(t *testing.T, text string, want, got *peg.Node, wantRanges, gotRanges map[*peg.Node]parser.Range) {
+	t.Helper()
+	if got.Name != want.Name || got.Text != want.Text || len(got.Kids) != len(want.Kids) {
+		t.Errorf("Decode(Encode(%q)) has %s %q with %d kids, want %s %q with %d kids",
+			text, got.Name, got.Text, len(got.Kids), want.Name, want.Text, len(want.Kids))
+		return
+	}
+	w, wok := wantRanges[want]
+	g, gok := gotRanges[got]
+	if w != g || wok != gok {
+		t.Errorf("Decode(Encode(%q)) range of %s %q=%+v, %v, want %+v, %v", text, want.Name, want.Text, g, gok, w, wok)
+	}
+	for i := range want.Kids {
+		checkTree(t, text, want.Kids[i], got.Kids[i], wantRanges, gotRanges)
+	}
+}
+
+func TestElided(t *testing.T) {
+	const text = "lo mlatu ku cu sipna"
+	tree, err := parser.Parse("camxes", text)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", text, err)
+	}
+	ranges := parser.Ranges(text, tree)
+	parser.AddElidedTerminators(tree)
+	for _, ranges := range []map[*peg.Node]parser.Range{nil, ranges} {
+		var elided []string
+		var visit func(*jsontree.Node)
+		visit = func(n *jsontree.Node) {
+			if n.Elided {
+				elided = append(elided, n.Name)
+			}
+			for _, k := range n.Kids {
+				visit(k)
+			}
+		}
+		visit(jsontree.FromTree(tree, ranges))
+		// The ku is written, and the cu is not elided.
+		if want := []string{"VAU_elidible"}; !reflect.DeepEqual(elided, want) {
+			t.Errorf("FromTree(%q) elided=%q, want %q", text, elided, want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	word := &parser.Error{
+		Loc:      parser.Loc{Byte: 3, Rune: 3, Line: 1, Column: 4, UTF16: 3, UTF16Column: 4},
+		FilePath: "text.jbo",
+		Want:     []string{"KU", "selbri"},
+	}
+	raw := &parser.Error{
+		Loc:  parser.Loc{Byte: 5, Rune: 5, Line: 1, Column: 6, UTF16: 5, UTF16Column: 6},
+		Want: []string{"space"},
+	}
+	tests := []struct {
+		err  error
+		want string
+		ok   bool
+	}{
+		{
+			err:  word,
+			want: `{"errors":[{"file":"text.jbo","location":{"byte":3,"rune":3,"line":1,"column":4,"utf16":3,"utf16Column":4},"want":["KU","selbri"]}]}`,
+			ok:   true,
+		},
+		{
+			err: &parser.ErrorPair{Word: word, Raw: raw},
+			want: `{"errors":[` +
+				`{"file":"text.jbo","location":{"byte":3,"rune":3,"line":1,"column":4,"utf16":3,"utf16Column":4},"want":["KU","selbri"],"kind":"word"},` +
+				`{"location":{"byte":5,"rune":5,"line":1,"column":6,"utf16":5,"utf16Column":6},"want":["space"],"kind":"raw"}]}`,
+			ok: true,
+		},
+		{
+			err: parser.ErrorList{raw, word},
+			want: `{"errors":[` +
+				`{"location":{"byte":5,"rune":5,"line":1,"column":6,"utf16":5,"utf16Column":6},"want":["space"]},` +
+				`{"file":"text.jbo","location":{"byte":3,"rune":3,"line":1,"column":4,"utf16":3,"utf16Column":4},"want":["KU","selbri"]}]}`,
+			ok: true,
+		},
+		{err: errors.New("not a syntax error"), ok: false},
+		{err: parser.ErrorList{word, errors.New("not a syntax error")}, ok: false},
+	}
+	for _, test := range tests {
+		errs, ok := jsontree.FromErrors(test.err)
+		if ok != test.ok {
+			t.Errorf("FromErrors(%v) ok=%v, want %v", test.err, ok, test.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		var b bytes.Buffer
+		if err := jsontree.Encode(&b, &jsontree.Result{Errors: errs}); err != nil {
+			t.Errorf("Encode(%v) failed: %v", test.err, err)
+			continue
+		}
+		if got := strings.TrimSpace(b.String()); got != test.want {
+			t.Errorf("Encode(%v)=\n%s\nwant\n%s", test.err, got, test.want)
+		}
+		res, err := jsontree.Decode(&b)
+		if err != nil {
+			t.Errorf("Decode(Encode(%v)) failed: %v", test.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(res.Errors, errs) {
+			t.Errorf("Decode(Encode(%v))=%+v, want %+v", test.err, res.Errors, errs)
+		}
+		for i, e := range res.Errors {
+			if got := e.ParserError(); !reflect.DeepEqual(got, errs[i].ParserError()) {
+				t.Errorf("Decode(Encode(%v)) error %d=%+v, want %+v", test.err, i, got, errs[i].ParserError())
+			}
+		}
+	}
+	if got := (&jsontree.Error{File: word.FilePath, Location: jsontree.FromLoc(word.Loc), Want: word.Want}).ParserError(); !reflect.DeepEqual(got, word) {
+		t.Errorf("ParserError()=%+v, want %+v", got, word)
+	}
+}
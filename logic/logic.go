@@ -0,0 +1,660 @@
+// Package logic translates parsed Lojban into formulas of first-order logic,
+// following The Complete Lojban Language, chapters 14 and 16.
+//
+// The translation supports a subset of Lojban:
+//
+//   - bridi, whose selbri is the predicate and whose places are its arguments,
+//     assigned by package places;
+//   - the variables da, de, and di, bound where they first appear,
+//     existentially, or universally with ro, and negated with no;
+//   - prenexes of variables and na ku before zo'u;
+//   - negation with na before the selbri, which negates the whole bridi,
+//     and with na ku, which negates what follows it;
+//   - the logical connectives of sentences (.i je), bridi tails (gi'e),
+//     and sumti (.e), in afterthought, and in forethought (ge … gi).
+//
+// Each sumti that is not a variable is a constant, written as in the text.
+// Free modifiers, such as vocatives, have no logical content and are ignored.
+// Other constructs, such as tenses, modal tags, and quantified descriptions,
+// are reported by an *UnsupportedError.
+package logic
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/ast"
+	"within.website/johaus/parser"
+	"within.website/johaus/parser/morph"
+	"within.website/johaus/places"
+)
+
+// A Kind is the kind of a Formula.
+type Kind int
+
+// The kinds of formulas.
+const (
+	// Atom is a predicate applied to its arguments, such as klama(mi, lo zarci).
+	Atom Kind = iota
+	// Not is the negation of its operand.
+	Not
+	// And is the conjunction of its two operands.
+	And
+	// Or is the disjunction of its two operands.
+	Or
+	// Iff is the biconditional of its two operands.
+	Iff
+	// Exists is the existential quantification of its operand over its variable.
+	Exists
+	// ForAll is the universal quantification of its operand over its variable.
+	ForAll
+)
+
+// A Formula is a formula of first-order logic.
+type Formula struct {
+	// Kind is the kind of the formula.
+	Kind Kind
+
+	// Predicate is the predicate of an Atom:
+	// the brivla of its selbri, such as klama,
+	// or the whole selbri of a tanru, such as sutra klama.
+	Predicate string
+
+	// Args are the arguments of an Atom, in the order of the places of its predicate.
+	// A place that is not filled up to the last filled place is the constant zo'e.
+	// If the predicate is a brivla, its places are those of the brivla
+	// without conversions: mi se klama do is klama(do, mi).
+	Args []Term
+
+	// Var is the variable of an Exists or ForAll formula.
+	Var string
+
+	// Operands are the operands:
+	// one for Not, Exists, and ForAll, and two for And, Or, and Iff.
+	Operands []*Formula
+}
+
+// A Term is the argument of an Atom: a variable or a constant.
+type Term struct {
+	// Name is the variable, such as da, or the sumti of the constant,
+	// such as lo zarci, written in lowercase with ' for h.
+	Name string
+
+	// Variable is whether the term is a variable.
+	Variable bool
+}
+
+// An UnsupportedError is returned by Translate
+// for a construct that cannot be translated.
+type UnsupportedError struct {
+	// Construct describes the unsupported construct, such as tagged term.
+	Construct string
+
+	// Node is the parse tree node of the unsupported construct.
+	Node *peg.Node
+}
+
+func (err *UnsupportedError) Error() string {
+	text := strings.Trim(err.Node.Text, parser.SpaceChars)
+	return "logic: unsupported " + err.Construct + " " + strconv.Quote(text)
+}
+
+// Translate returns the formulas of the sentences of a parse tree,
+// converted by ast.Convert, which supports the camxes, camxes-beta,
+// and ilmentufa dialects.
+// If ast.Convert returns an error, Translate returns nil and the error.
+// Otherwise it is as TranslateText.
+func Translate(tree *peg.Node) ([]*Formula, error) {
+	t, err := ast.Convert(tree)
+	if err != nil {
+		return nil, err
+	}
+	return TranslateText(t)
+}
+
+// TranslateText returns the formulas of the sentences of the text,
+// one for each sentence, or for each sentences connected by ijek connectives.
+// Variables are bound within the sentence where they appear.
+//
+// If a sentence contains a construct that cannot be translated,
+// TranslateText returns the formulas of the other sentences
+// and an *UnsupportedError for the first such construct.
+func TranslateText(text *ast.Text) ([]*Formula, error) {
+	t := translator{
+		places: make(map[*ast.Selbri]places.Bridi),
+		joins:  make(map[*ast.Sumti]func(l, r *Formula) *Formula),
+	}
+	for _, b := range places.AssignText(text) {
+		if b.Sentence != nil {
+			t.places[b.Selbri] = b
+		}
+	}
+	var fs []*Formula
+	var first error
+	for _, p := range text.Paragraphs {
+		for _, ss := range chains(p.Sentences) {
+			t.err = nil
+			f := t.chain(ss, make(map[string]bool))
+			if t.err != nil {
+				if first == nil {
+					first = t.err
+				}
+				continue
+			}
+			fs = append(fs, f(nil))
+		}
+	}
+	return fs, first
+}
+
+// chains splits sentences into runs connected by ijek connectives.
+func chains(ss []*ast.Sentence) [][]*ast.Sentence {
+	var cs [][]*ast.Sentence
+	for _, s := range ss {
+		if s.Connective == nil || len(cs) == 0 {
+			cs = append(cs, nil)
+		}
+		cs[len(cs)-1] = append(cs[len(cs)-1], s)
+	}
+	return cs
+}
+
+type translator struct {
+	// places are the place assignments of the bridi, by their selbri.
+	places map[*ast.Selbri]places.Bridi
+
+	// joins are the truth functions of the connected sumti.
+	joins map[*ast.Sumti]func(l, r *Formula) *Formula
+
+	err *UnsupportedError
+}
+
+func (t *translator) unsupported(construct string, n *peg.Node) {
+	if t.err == nil {
+		t.err = &UnsupportedError{Construct: construct, Node: n}
+	}
+}
+
+// A builder builds a formula
+// with each connected sumti replaced by the operand chosen for it.
+//
+// A connected sumti, such as mi .e do, is expanded
+// into a connection of formulas, one for each of its operands,
+// where the scope of the sumti begins.
+type builder func(choice map[*ast.Sumti]int) *Formula
+
+// A scope is an operator whose scope is the rest of the bridi:
+// a quantifier, a negation, or a connected sumti.
+type scope struct {
+	// kind is Exists, ForAll, or Not.
+	kind Kind
+
+	// v is the variable of Exists and ForAll.
+	v string
+
+	// sumti is a connected sumti, or nil.
+	sumti *ast.Sumti
+}
+
+// wrapped returns a builder of the body within the scopes, outermost first.
+func (t *translator) wrapped(scopes []scope, body builder) builder {
+	if len(scopes) == 0 {
+		return body
+	}
+	return func(choice map[*ast.Sumti]int) *Formula {
+		return t.wrap(scopes, choice, body)
+	}
+}
+
+func (t *translator) wrap(scopes []scope, choice map[*ast.Sumti]int, body builder) *Formula {
+	if len(scopes) == 0 {
+		return body(choice)
+	}
+	sc, rest := scopes[0], scopes[1:]
+	if sc.sumti == nil {
+		return &Formula{Kind: sc.kind, Var: sc.v, Operands: []*Formula{t.wrap(rest, choice, body)}}
+	}
+	var ops [2]*Formula
+	for i, u := range sc.sumti.Sumti {
+		c := make(map[*ast.Sumti]int, len(choice)+1)
+		for s, j := range choice {
+			c[s] = j
+		}
+		c[sc.sumti] = i
+		r := rest
+		if u.Kind == ast.ConnectedSumti {
+			// The scope of a connected operand begins with it.
+			r = append([]scope{{sumti: u}}, rest...)
+		}
+		ops[i] = t.wrap(r, c, body)
+	}
+	return t.joins[sc.sumti](ops[0], ops[1])
+}
+
+// connected returns a builder of the connection of two builders.
+func connected(join func(l, r *Formula) *Formula, l, r builder) builder {
+	return func(choice map[*ast.Sumti]int) *Formula {
+		return join(l(choice), r(choice))
+	}
+}
+
+// branch returns the builder of an operand of a connection,
+// with its own copy of the bound variables,
+// reporting a variable bound by more than one operand,
+// whose variables are introduced.
+func (t *translator) branch(bound, introduced map[string]bool, n *peg.Node, f func(map[string]bool) builder) builder {
+	b := make(map[string]bool, len(bound))
+	for v := range bound {
+		b[v] = true
+	}
+	out := f(b)
+	var vs []string
+	for v := range b {
+		if !bound[v] {
+			vs = append(vs, v)
+		}
+	}
+	sort.Strings(vs)
+	for _, v := range vs {
+		if introduced[v] {
+			t.unsupported("variable "+v+" bound in more than one operand", n)
+		}
+		introduced[v] = true
+	}
+	return out
+}
+
+// text returns the builder of the conjunction of the sentences of a text,
+// such as a tu'e … tu'u group.
+func (t *translator) text(text *ast.Text, bound map[string]bool) builder {
+	var f builder
+	for _, p := range text.Paragraphs {
+		for _, ss := range chains(p.Sentences) {
+			g := t.chain(ss, bound)
+			if f == nil {
+				f = g
+				continue
+			}
+			f = connected(and, f, g)
+		}
+	}
+	if f == nil {
+		t.unsupported("empty group", text.Tree)
+	}
+	return f
+}
+
+func and(l, r *Formula) *Formula {
+	return &Formula{Kind: And, Operands: []*Formula{l, r}}
+}
+
+// chain returns the builder of sentences connected by ijek connectives.
+func (t *translator) chain(ss []*ast.Sentence, bound map[string]bool) builder {
+	introduced := make(map[string]bool)
+	var f builder
+	for i, s := range ss {
+		s := s
+		g := t.branch(bound, introduced, s.Tree, func(b map[string]bool) builder {
+			return t.sentence(s, b)
+		})
+		if i == 0 {
+			f = g
+			continue
+		}
+		f = connected(t.connective(s.Connective, s.Tree), f, g)
+	}
+	return f
+}
+
+func (t *translator) sentence(s *ast.Sentence, bound map[string]bool) builder {
+	switch {
+	case s.Group != nil:
+		if s.Tag != nil {
+			t.unsupported("tagged group", s.Tree)
+			return nil
+		}
+		scopes := t.prenex(s.Prenex, bound)
+		return t.wrapped(scopes, t.text(s.Group, bound))
+	case s.Tail == nil:
+		t.unsupported("fragment", s.Tree)
+		return nil
+	}
+	simple := s.Tail.Selbri != nil
+	var scopes []scope
+	if simple {
+		// The na of a simple bridi negates all of it,
+		// as if it were na ku at the beginning of the prenex.
+		scopes = t.negations(s.Tail.Selbri.NA, s.Tail.Selbri.Tree)
+	}
+	scopes = append(scopes, t.prenex(s.Prenex, bound)...)
+	scopes = append(scopes, t.terms(s.Terms, bound)...)
+	return t.wrapped(scopes, t.tail(s.Tail, bound, simple))
+}
+
+// tail returns the builder of a bridi tail.
+// The NA of the selbri of a simple tail
+// is in the scopes of the sentence and not of the tail.
+func (t *translator) tail(bt *ast.BridiTail, bound map[string]bool, simple bool) builder {
+	if bt.Selbri != nil {
+		var scopes []scope
+		if !simple {
+			scopes = t.negations(bt.Selbri.NA, bt.Selbri.Tree)
+		}
+		scopes = append(scopes, t.terms(bt.Head, bound)...)
+		scopes = append(scopes, t.terms(bt.Terms, bound)...)
+		links, atom := t.atom(bt.Selbri, bound)
+		return t.wrapped(append(scopes, links...), atom)
+	}
+	// The terms shared by the connected tails are in scope over the connection.
+	scopes := t.terms(bt.Terms, bound)
+	introduced := make(map[string]bool)
+	var ops []builder
+	for _, u := range bt.Tails {
+		u := u
+		ops = append(ops, t.branch(bound, introduced, u.Tree, func(b map[string]bool) builder {
+			return t.tail(u, b, false)
+		}))
+	}
+	for _, u := range bt.Sentences {
+		u := u
+		ops = append(ops, t.branch(bound, introduced, u.Tree, func(b map[string]bool) builder {
+			return t.sentence(u, b)
+		}))
+	}
+	if len(ops) != 2 {
+		t.unsupported("connected bridi tail", bt.Tree)
+		return nil
+	}
+	return t.wrapped(scopes, connected(t.connective(bt.Connective, bt.Tree), ops[0], ops[1]))
+}
+
+// atom returns the scopes of the terms linked to the selbri by be … bei,
+// and the builder of the atom of the selbri.
+func (t *translator) atom(sel *ast.Selbri, bound map[string]bool) ([]scope, builder) {
+	b, ok := t.places[sel]
+	switch {
+	case sel.Tag != nil:
+		t.unsupported("tagged selbri", sel.Tree)
+		return nil, nil
+	case !ok || b.Head == nil:
+		t.unsupported("connected selbri", sel.Tree)
+		return nil, nil
+	}
+	type arg struct {
+		place int
+		sumti *ast.Sumti
+	}
+	var name string
+	var scopes []scope
+	var args []arg
+	if us := sel.Tanru.Units; len(us) == 1 && us[0] == b.Head && b.Head.Word != "" && len(b.Head.NAhE) == 0 {
+		// The places of a brivla are its own, without its conversions.
+		name = morph.Normalize(b.Head.Word)
+		scopes = t.terms(b.Head.Links, bound)
+		for _, p := range b.Places {
+			switch {
+			case p.Place == 0:
+			case p.Underlying == 0:
+				t.unsupported("conversion", sel.Tree)
+			default:
+				args = append(args, arg{p.Underlying, p.Term.Sumti})
+			}
+		}
+	} else {
+		// The places of a tanru, or other selbri, are those of the selbri as written,
+		// including its conversions and linked terms.
+		name = unparse(sel.Tanru.Tree)
+		if hasVariable(sel.Tanru.Tree) {
+			t.unsupported("variable within a selbri", sel.Tree)
+		}
+		linked := make(map[*ast.Term]bool)
+		addLinks(linked, sel.Tanru)
+		for _, p := range b.Places {
+			if p.Place > 0 && !linked[p.Term] {
+				args = append(args, arg{p.Place, p.Term.Sumti})
+			}
+		}
+	}
+	n := 0
+	filled := make(map[int]bool)
+	for _, a := range args {
+		if filled[a.place] {
+			t.unsupported("place x"+strconv.Itoa(a.place)+" filled more than once", sel.Tree)
+		}
+		filled[a.place] = true
+		if a.place > n {
+			n = a.place
+		}
+	}
+	return scopes, func(choice map[*ast.Sumti]int) *Formula {
+		f := &Formula{Kind: Atom, Predicate: name, Args: make([]Term, n)}
+		for i := range f.Args {
+			f.Args[i] = Term{Name: "zo'e"}
+		}
+		for _, a := range args {
+			f.Args[a.place-1] = value(a.sumti, choice)
+		}
+		return f
+	}
+}
+
+// addLinks adds the be … bei terms of the units of the tanru.
+func addLinks(linked map[*ast.Term]bool, t *ast.Tanru) {
+	if t == nil {
+		return
+	}
+	for _, op := range t.Tanru {
+		addLinks(linked, op)
+	}
+	for _, u := range t.Units {
+		addLinks(linked, u.Group)
+		for _, l := range u.Links {
+			linked[l] = true
+		}
+	}
+}
+
+// value returns the term of the sumti with the chosen operand of each connected sumti.
+func value(s *ast.Sumti, choice map[*ast.Sumti]int) Term {
+	for s.Kind == ast.ConnectedSumti {
+		s = s.Sumti[choice[s]]
+	}
+	if v := variable(s); v != "" {
+		return Term{Name: v, Variable: true}
+	}
+	return Term{Name: unparse(s.Tree)}
+}
+
+// prenex returns the scopes of the terms of a prenex,
+// which may be variables and na ku.
+func (t *translator) prenex(ts []*ast.Term, bound map[string]bool) []scope {
+	for _, u := range ts {
+		if u.NA == "" && (u.Sumti == nil || u.Tag != nil || u.FA != "" || variable(u.Sumti) == "") {
+			t.unsupported("prenex term", u.Tree)
+		}
+	}
+	return t.terms(ts, bound)
+}
+
+// terms returns the scopes of the terms, in order,
+// binding the variables that first appear in them.
+func (t *translator) terms(ts []*ast.Term, bound map[string]bool) []scope {
+	var scopes []scope
+	for _, u := range ts {
+		switch {
+		case u.Tag != nil:
+			t.unsupported("tagged term", u.Tree)
+		case u.NA != "":
+			scopes = append(scopes, t.negations([]string{u.NA}, u.Tree)...)
+		case u.Sumti == nil:
+			t.unsupported("termset", u.Tree)
+		case morph.Normalize(u.FA) == "fi'a":
+			t.unsupported("fi'a term", u.Tree)
+		default:
+			scopes = append(scopes, t.sumti(u.Sumti, bound, false)...)
+		}
+	}
+	return scopes
+}
+
+// sumti returns the scopes of the sumti:
+// its quantifier, if it is a variable that is not yet bound,
+// or, for a connected sumti, the scopes of its operands followed by itself,
+// unless it is the operand of another connected sumti.
+func (t *translator) sumti(s *ast.Sumti, bound map[string]bool, operand bool) []scope {
+	if s.Kind == ast.ConnectedSumti {
+		var scopes []scope
+		for _, u := range s.Sumti {
+			scopes = append(scopes, t.sumti(u, bound, true)...)
+		}
+		t.joins[s] = t.connective(s.Connective, s.Tree)
+		if !operand {
+			scopes = append(scopes, scope{sumti: s})
+		}
+		return scopes
+	}
+	v := variable(s)
+	if v == "" {
+		switch {
+		case s.Quantifier != "":
+			t.unsupported("quantified sumti", s.Tree)
+		case hasVariable(s.Tree):
+			t.unsupported("variable within a sumti", s.Tree)
+		}
+		return nil
+	}
+	if len(s.RelativeClauses) > 0 || len(s.Free) > 0 {
+		t.unsupported("restricted variable", s.Tree)
+	}
+	q := morph.Normalize(s.Quantifier)
+	if bound[v] {
+		if q != "" {
+			t.unsupported("quantifier of a bound variable", s.Tree)
+		}
+		return nil
+	}
+	bound[v] = true
+	switch q {
+	case "", "su'o":
+		return []scope{{kind: Exists, v: v}}
+	case "ro":
+		return []scope{{kind: ForAll, v: v}}
+	case "no":
+		return []scope{{kind: Not}, {kind: Exists, v: v}}
+	}
+	t.unsupported("quantifier "+q, s.Tree)
+	return nil
+}
+
+// negations returns the scopes of NA words: a negation for each na.
+func (t *translator) negations(na []string, n *peg.Node) []scope {
+	var scopes []scope
+	for _, w := range na {
+		switch morph.Normalize(w) {
+		case "na":
+			scopes = append(scopes, scope{kind: Not})
+		case "ja'a":
+		default:
+			t.unsupported("negation "+morph.Normalize(w), n)
+		}
+	}
+	return scopes
+}
+
+// connective returns the truth function of a logical connective
+// whose operands are beneath the node n.
+func (t *translator) connective(c *ast.Connective, n *peg.Node) func(l, r *Formula) *Formula {
+	switch {
+	case c == nil:
+		t.unsupported("connective", n)
+		return nil
+	case c.Tag != nil:
+		t.unsupported("tagged connective", c.Tree)
+		return nil
+	}
+	switch c.Selmaho {
+	case "A", "JA", "GIhA", "GA":
+	default:
+		t.unsupported("non-logical connective", c.Tree)
+		return nil
+	}
+	w := morph.Normalize(c.Word)
+	if w == "" {
+		t.unsupported("connective", c.Tree)
+		return nil
+	}
+	var kind Kind
+	switch w[len(w)-1] {
+	case 'a':
+		kind = Or
+	case 'e':
+		kind = And
+	case 'o':
+		kind = Iff
+	case 'u':
+		// A u B is A, whether or not B.
+	default:
+		t.unsupported("connective "+w, c.Tree)
+		return nil
+	}
+	left, right := c.NA || c.NAIFirst, c.NAI
+	swap := false
+	switch morph.Normalize(c.SE) {
+	case "":
+	case "se":
+		swap = true
+	default:
+		t.unsupported("conversion "+morph.Normalize(c.SE), c.Tree)
+		return nil
+	}
+	return func(l, r *Formula) *Formula {
+		if left {
+			l = &Formula{Kind: Not, Operands: []*Formula{l}}
+		}
+		if right {
+			r = &Formula{Kind: Not, Operands: []*Formula{r}}
+		}
+		if swap {
+			l, r = r, l
+		}
+		if w[len(w)-1] == 'u' {
+			return l
+		}
+		return &Formula{Kind: kind, Operands: []*Formula{l, r}}
+	}
+}
+
+// variable returns the variable of a sumti that is da, de, or di,
+// or the empty string.
+func variable(s *ast.Sumti) string {
+	if s.Kind != ast.ProSumti || len(s.Words) != 1 {
+		return ""
+	}
+	switch w := morph.Normalize(s.Words[0]); w {
+	case "da", "de", "di":
+		return w
+	}
+	return ""
+}
+
+// hasVariable returns whether the tree contains da, de, or di.
+func hasVariable(n *peg.Node) bool {
+	if n.Name == "KOhA" {
+		switch morph.Normalize(n.Text) {
+		case "da", "de", "di":
+			return true
+		}
+	}
+	for _, k := range n.Kids {
+		if hasVariable(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func unparse(n *peg.Node) string {
+	return strings.TrimSpace(parser.UnparseOptions{Normalize: true}.Unparse(n))
+}
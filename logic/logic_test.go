@@ -0,0 +1,93 @@
+package logic_test
+
+import (
+	"strings"
+	"testing"
+
+	"within.website/johaus/logic"
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+	"within.website/johaus/pretty"
+)
+
+func TestTranslate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "mi klama lo zarci", want: "klama(mi, lo zarci)"},
+		{text: "mi na klama", want: "¬klama(mi)"},
+
+		// Variables are bound where they first appear.
+		{text: "da klama", want: "∃da klama(da)"},
+		{text: "su'o da klama", want: "∃da klama(da)"},
+		{text: "ro da klama", want: "∀da klama(da)"},
+		{text: "ro di klama", want: "∀di klama(di)"},
+		{text: "no da klama", want: "¬∃da klama(da)"},
+		{text: "da de prami", want: "∃da ∃de prami(da, de)"},
+		{text: "ro da de prami", want: "∀da ∃de prami(da, de)"},
+		{text: "de prami ro da", want: "∃de ∀da prami(de, da)"},
+
+		// Prenexes.
+		{text: "ro da zo'u da klama", want: "∀da klama(da)"},
+		{text: "ro da su'o de zo'u da prami de", want: "∀da ∃de prami(da, de)"},
+		{text: "su'o de ro da zo'u da prami de", want: "∃de ∀da prami(da, de)"},
+		{text: "na ku zo'u mi klama", want: "¬klama(mi)"},
+		{text: "ro da zo'u na ku de prami da", want: "∀da ¬∃de prami(de, da)"},
+
+		// na ku negates what follows it.
+		{text: "na ku ro da klama", want: "¬∀da klama(da)"},
+		{text: "ro da na ku klama", want: "∀da ¬klama(da)"},
+
+		// Connectives.
+		{text: "ro da klama gi'e citka", want: "∀da (klama(da) ∧ citka(da))"},
+		{text: "mi klama .i ja do klama", want: "klama(mi) ∨ klama(do)"},
+		{text: "mi .o do klama", want: "klama(mi) ↔ klama(do)"},
+		{text: "mi na.e do klama", want: "¬klama(mi) ∧ klama(do)"},
+		{text: "ge nai mi gi do klama", want: "¬klama(mi) ∧ klama(do)"},
+		{text: "ge mi gi nai do klama", want: "klama(mi) ∧ ¬klama(do)"},
+		{text: "ga nai mi gi nai do klama", want: "¬klama(mi) ∨ ¬klama(do)"},
+
+		// Each sentence is a formula.
+		{text: "mi prami ro da .i da klama", want: "∀da prami(mi, da)\n∃da klama(da)"},
+	}
+	for _, test := range tests {
+		tree, err := parser.Parse("camxes", test.text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.text, err)
+			continue
+		}
+		fs, err := logic.Translate(tree)
+		if err != nil {
+			t.Errorf("Translate(%q) failed: %v", test.text, err)
+			continue
+		}
+		var b strings.Builder
+		if err := pretty.Logic(&b, fs); err != nil {
+			t.Errorf("Logic(%q) failed: %v", test.text, err)
+			continue
+		}
+		if got := strings.TrimSuffix(b.String(), "\n"); got != test.want {
+			t.Errorf("Translate(%q)=%q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestTranslateUnsupported(t *testing.T) {
+	for _, text := range []string{
+		"pa da klama",
+		"ro da poi prenu cu klama",
+		"mi klama pi'o lo karce",
+	} {
+		tree, err := parser.Parse("camxes", text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", text, err)
+			continue
+		}
+		if _, err := logic.Translate(tree); err == nil {
+			t.Errorf("Translate(%q) succeeded, want an *UnsupportedError", text)
+		} else if _, ok := err.(*logic.UnsupportedError); !ok {
+			t.Errorf("Translate(%q) error=%v, want an *UnsupportedError", text, err)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/ast"
+	"within.website/johaus/parser"
+)
+
+// A document is an open text document and the result of parsing it.
+type document struct {
+	uri   string
+	text  string
+	index *parser.LineIndex
+
+	// spans are the spans of the text that parsed.
+	spans []span
+
+	// errs are the errors from parsing the text.
+	errs []error
+}
+
+// A span is a span of a document that parsed.
+type span struct {
+	// byte is the byte offset of the span in the document.
+	byte int
+
+	tree *peg.Node
+
+	// ranges are the Ranges of the nodes of tree,
+	// relative to the beginning of the span.
+	ranges map[*peg.Node]parser.Range
+}
+
+// parseTimeout is the maximum time to spend parsing a document.
+const parseTimeout = 10 * time.Second
+
+// parseOptions limits the size of the documents parsed by the server,
+// so that a large document cannot use all of its memory.
+// A parse that is canceled or times out still runs until the parser
+// next checks its context, as described for parser.Options,
+// so these limits also bound the work of abandoned parses.
+var parseOptions = parser.Options{
+	MaxBytes:     32 << 10,
+	MaxMemoBytes: 256 << 20,
+}
+
+func newDocument(uri, text string) *document {
+	return &document{uri: uri, text: text, index: parser.NewLineIndex(text)}
+}
+
+// parse parses the document, recovering from syntax errors
+// so that all of them are reported,
+// and returns the spans that parsed and the errors.
+// It does not modify the document,
+// so it can run while the document is in use.
+func (doc *document) parse(ctx context.Context, dialect string) ([]span, []error) {
+	ctx, cancel := context.WithTimeout(ctx, parseTimeout)
+	defer cancel()
+	parsed, err := parser.ParseRecover(ctx, dialect, doc.text, parseOptions)
+	var spans []span
+	for _, s := range parsed {
+		spans = append(spans, span{
+			byte:   s.Byte,
+			tree:   s.Tree,
+			ranges: parser.Ranges(s.Tree.Text, s.Tree),
+		})
+	}
+	switch err := err.(type) {
+	case nil:
+		return spans, nil
+	case parser.ErrorList:
+		return spans, err
+	}
+	if err == context.DeadlineExceeded {
+		err = errParseTimeout
+	}
+	return spans, []error{err}
+}
+
+// errParseTimeout is the error of a parse that took longer than parseTimeout.
+var errParseTimeout = errors.New("parse timed out")
+
+func (doc *document) diagnostics() []diagnostic {
+	diags := []diagnostic{}
+	for _, err := range doc.errs {
+		d := diagnostic{Severity: severityError, Source: "johaus", Message: err.Error()}
+		if pair, ok := err.(*parser.ErrorPair); ok {
+			err = pair.Word
+		}
+		if err, ok := err.(*parser.Error); ok {
+			// Underline the word at the error.
+			end := err.Byte
+			for end < len(doc.text) && !strings.ContainsRune(parser.SpaceChars, rune(doc.text[end])) {
+				end++
+			}
+			d.Range = doc.lspRange(err.Byte, end)
+			d.Message = err.Expected()
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// hover returns the grammar rules and selma'o of the word at the position,
+// or nil if there is no word there.
+func (doc *document) hover(pos position) *hover {
+	byte := doc.index.UTF16Byte(pos.Line+1, pos.Character+1)
+	for _, s := range doc.spans {
+		if byte < s.byte || byte >= s.byte+len(s.tree.Text) {
+			continue
+		}
+		var path []string
+		for n := s.tree; n != nil; n = s.kidAt(n, byte-s.byte) {
+			if n.Name == "" || isClause(n.Name) {
+				continue
+			}
+			path = append(path, n.Name)
+			if parser.IsWord(n) {
+				r := s.ranges[n]
+				return &hover{
+					Contents: markupContent{
+						Kind: "markdown",
+						Value: "**" + n.Name + "** " + "`" + n.Text + "`" +
+							"\n\n" + strings.Join(path, " › "),
+					},
+					Range: doc.lspRange(s.byte+r.Start.Byte, s.byte+r.End.Byte),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// kidAt returns the kid of n containing the byte offset into the span,
+// or nil if there is none.
+func (s *span) kidAt(n *peg.Node, byte int) *peg.Node {
+	for _, k := range n.Kids {
+		if r := s.ranges[k]; r.Start.Byte <= byte && byte < r.End.Byte {
+			return k
+		}
+	}
+	return nil
+}
+
+// symbols returns a symbol for each paragraph with a symbol for each of its sentences.
+func (doc *document) symbols() []documentSymbol {
+	syms := []documentSymbol{}
+	for _, s := range doc.spans {
+		text, err := ast.Convert(s.tree)
+		if err != nil {
+			// The dialect's grammar is not supported by ast,
+			// so use its paragraph and statement rules.
+			syms = append(syms, s.treeSymbols(doc)...)
+			continue
+		}
+		for _, p := range text.Paragraphs {
+			para := s.symbol(doc, p.Tree, symbolNamespace)
+			for _, sentence := range p.Sentences {
+				para.Children = append(para.Children, s.symbol(doc, sentence.Tree, symbolObject))
+			}
+			syms = append(syms, para)
+		}
+	}
+	return syms
+}
+
+// treeSymbols returns a symbol for each paragraph node of the tree of the span
+// with a symbol for each of its statement nodes,
+// not including those in quotations and other nested text.
+func (s *span) treeSymbols(doc *document) []documentSymbol {
+	var syms []documentSymbol
+	for _, p := range outermost(nil, s.tree, "paragraph") {
+		para := s.symbol(doc, p, symbolNamespace)
+		for _, sentence := range outermost(nil, p, "statement") {
+			para.Children = append(para.Children, s.symbol(doc, sentence, symbolObject))
+		}
+		syms = append(syms, para)
+	}
+	return syms
+}
+
+// outermost appends to ns the nodes of the tree for the rule, as by parser.Rule,
+// that are not within another node for the rule.
+func outermost(ns []*peg.Node, n *peg.Node, rule string) []*peg.Node {
+	if parser.Rule(n.Name) == rule {
+		return append(ns, n)
+	}
+	for _, k := range n.Kids {
+		ns = outermost(ns, k, rule)
+	}
+	return ns
+}
+
+func (s *span) symbol(doc *document, n *peg.Node, kind int) documentSymbol {
+	const maxRunes = 40
+	text := strings.Trim(n.Text, parser.SpaceChars)
+	name := strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(name) > maxRunes {
+		name = string([]rune(name)[:maxRunes]) + "…"
+	}
+	// The range excludes leading and trailing spaces and pauses.
+	start := s.byte + s.ranges[n].Start.Byte + len(n.Text) - len(strings.TrimLeft(n.Text, parser.SpaceChars))
+	rng := doc.lspRange(start, start+len(text))
+	return documentSymbol{Name: name, Kind: kind, Range: rng, SelectionRange: rng}
+}
+
+// folds maps the selma'o opening foldable constructs
+// to the selma'o of their terminators.
+// Quotations without a terminator are single words.
+var folds = map[string]string{
+	"LU":   "LIhU",
+	"TUhE": "TUhU",
+	"LOhU": "",
+	"ZOI":  "",
+}
+
+// foldingRanges returns the folding ranges of the multi-line quotations and tu'e … tu'u groups.
+func (doc *document) foldingRanges() []foldingRange {
+	folding := []foldingRange{}
+	for _, s := range doc.spans {
+		var visit func(*peg.Node)
+		visit = func(n *peg.Node) {
+			kids := flatten(n.Kids)
+			for i, k := range kids {
+				term, ok := folds[parser.Rule(k.Name)]
+				if !ok || parser.IsWordRule(k.Name) {
+					// The word of the clause, within the clause.
+					continue
+				}
+				end := s.ranges[k].End
+				for _, k := range kids[i+1:] {
+					if term == "" {
+						break
+					}
+					if k.Text != "" {
+						end = s.ranges[k].End
+					}
+					if parser.Rule(k.Name) == term {
+						break
+					}
+				}
+				start := doc.index.Loc(s.byte + s.ranges[k].Start.Byte)
+				if end := doc.index.Loc(s.byte + end.Byte); end.Line > start.Line {
+					folding = append(folding, foldingRange{StartLine: start.Line - 1, EndLine: end.Line - 1})
+				}
+			}
+			for _, k := range n.Kids {
+				visit(k)
+			}
+		}
+		visit(s.tree)
+	}
+	return folding
+}
+
+// flatten returns the nodes with each anonymous node replaced by its kids.
+func flatten(nodes []*peg.Node) []*peg.Node {
+	var flat []*peg.Node
+	for _, n := range nodes {
+		if n.Name == "" {
+			flat = append(flat, flatten(n.Kids)...)
+		} else {
+			flat = append(flat, n)
+		}
+	}
+	return flat
+}
+
+func (doc *document) lspRange(start, end int) lspRange {
+	return lspRange{Start: doc.position(start), End: doc.position(end)}
+}
+
+func (doc *document) position(byte int) position {
+	loc := doc.index.Loc(byte)
+	return position{Line: loc.Line - 1, Character: loc.UTF16Column - 1}
+}
+
+// isClause returns whether the rule is a word clause or part of one,
+// such as KU_clause, KU_pre, KU_post, or KU_elidible, rather than a word.
+func isClause(name string) bool {
+	i := strings.LastIndexByte(name, '_')
+	return i > 0 && parser.IsWordRule(name[:i])
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func parsed(t *testing.T, dialect, text string) *document {
+	t.Helper()
+	doc := newDocument("file:///test.jbo", text)
+	doc.spans, doc.errs = doc.parse(context.Background(), dialect)
+	return doc
+}
+
+func TestHover(t *testing.T) {
+	tests := []struct {
+		text string
+		pos  position
+		// word is the start of the hover text, or "" if there is no hover.
+		word string
+		rng  lspRange
+	}{
+		{
+			text: "mi klama",
+			pos:  position{Line: 0, Character: 5},
+			word: "**BRIVLA** `klama`",
+			rng:  lspRange{Start: position{0, 3}, End: position{0, 8}},
+		},
+		{
+			text: "mi klama\n.i do citka",
+			pos:  position{Line: 1, Character: 3},
+			word: "**KOhA** `do`",
+			rng:  lspRange{Start: position{1, 3}, End: position{1, 5}},
+		},
+		{
+			text: "mi klama",
+			pos:  position{Line: 0, Character: 2},
+			word: "",
+		},
+	}
+	for _, test := range tests {
+		doc := parsed(t, "camxes", test.text)
+		h := doc.hover(test.pos)
+		switch {
+		case h == nil && test.word != "":
+			t.Errorf("hover(%q, %v)=nil, want %s", test.text, test.pos, test.word)
+		case h != nil && test.word == "":
+			t.Errorf("hover(%q, %v)=%q, want nil", test.text, test.pos, h.Contents.Value)
+		case h != nil && !strings.HasPrefix(h.Contents.Value, test.word):
+			t.Errorf("hover(%q, %v)=%q, want %s…", test.text, test.pos, h.Contents.Value, test.word)
+		case h != nil && h.Range != test.rng:
+			t.Errorf("hover(%q, %v).Range=%v, want %v", test.text, test.pos, h.Range, test.rng)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	const text = "mi klama .i do citka\nni'o ko'a prami lo'u mi klama .i do le'u"
+	want := []string{
+		"mi klama .i do citka",
+		"  mi klama",
+		"  do citka",
+		"ko'a prami lo'u mi klama .i do le'u",
+		"  ko'a prami lo'u mi klama .i do le'u",
+	}
+	// maftufa is not supported by ast,
+	// so its symbols come from the parse tree.
+	for _, dialect := range []string{"camxes", "ilmentufa", "maftufa"} {
+		doc := parsed(t, dialect, text)
+		if len(doc.errs) > 0 {
+			t.Errorf("%s: parse(%q) failed: %v", dialect, text, doc.errs)
+			continue
+		}
+		var got []string
+		for _, sym := range doc.symbols() {
+			got = append(got, sym.Name)
+			for _, kid := range sym.Children {
+				got = append(got, "  "+kid.Name)
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("%s: symbols(%q)=\n%s\nwant\n%s", dialect, text,
+				strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestFoldingRanges(t *testing.T) {
+	tests := []struct {
+		text string
+		want []foldingRange
+	}{
+		{text: "mi cusku lu do klama li'u", want: []foldingRange{}},
+		{
+			text: "mi cusku lu do klama\n.i do citka\nli'u",
+			want: []foldingRange{{StartLine: 0, EndLine: 2}},
+		},
+		{
+			text: "mi cusku lu do klama\n.i do citka",
+			want: []foldingRange{{StartLine: 0, EndLine: 1}},
+		},
+		{
+			text: "mi cusku zoi gy hello\nworld gy",
+			want: []foldingRange{{StartLine: 0, EndLine: 1}},
+		},
+		{
+			text: "tu'e mi klama\n.i do citka\ntu'u",
+			want: []foldingRange{{StartLine: 0, EndLine: 2}},
+		},
+	}
+	for _, test := range tests {
+		doc := parsed(t, "camxes", test.text)
+		if len(doc.errs) > 0 {
+			t.Errorf("parse(%q) failed: %v", test.text, doc.errs)
+			continue
+		}
+		got := doc.foldingRanges()
+		if !equalFolds(got, test.want) {
+			t.Errorf("foldingRanges(%q)=%v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func equalFolds(a, b []foldingRange) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDiagnostics(t *testing.T) {
+	doc := parsed(t, "camxes", "mi klama\n.i do cu cu citka\n.i mi citka")
+	diags := doc.diagnostics()
+	if len(diags) != 1 {
+		t.Fatalf("diagnostics()=%v, want 1 diagnostic", diags)
+	}
+	want := lspRange{Start: position{1, 9}, End: position{1, 11}}
+	if diags[0].Range != want {
+		t.Errorf("diagnostics()[0].Range=%v, want %v", diags[0].Range, want)
+	}
+	// The sentences around the error still parsed.
+	if len(doc.spans) != 2 {
+		t.Errorf("parse got %d spans, want 2", len(doc.spans))
+	}
+}
+
+func TestParseBudget(t *testing.T) {
+	text := strings.Repeat("mi klama .i ", parseOptions.MaxBytes/len("mi klama .i ")+1)
+	doc := parsed(t, "camxes", text)
+	if len(doc.errs) != 1 {
+		t.Fatalf("parse(%d bytes) got errors %v, want one *BudgetError", len(text), doc.errs)
+	}
+	if _, ok := doc.errs[0].(*parser.BudgetError); !ok {
+		t.Errorf("parse(%d bytes) error=%v, want a *BudgetError", len(text), doc.errs[0])
+	}
+}
+
+func TestParseCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	doc := newDocument("file:///test.jbo", "mi klama")
+	_, errs := doc.parse(ctx, "camxes")
+	if len(errs) != 1 || errs[0] != context.Canceled {
+		t.Errorf("parse with a canceled context got errors %v, want [%v]", errs, context.Canceled)
+	}
+}
+
+// TestSupersededParse tests that only the diagnostics
+// of the latest version of a document are kept.
+func TestSupersededParse(t *testing.T) {
+	var out bytes.Buffer
+	s := &server{
+		in:      bufio.NewReader(strings.NewReader("")),
+		out:     &out,
+		dialect: "camxes",
+		docs:    make(map[string]*document),
+		cancels: make(map[string]context.CancelFunc),
+	}
+	const uri = "file:///test.jbo"
+	s.mu.Lock()
+	open := didOpenTextDocumentParams{TextDocument: textDocumentItem{URI: uri, Text: "mi mi mi"}}
+	if err := s.serve(message(t, "textDocument/didOpen", open)); err != nil {
+		t.Fatalf("didOpen failed: %v", err)
+	}
+	change := didChangeTextDocumentParams{
+		TextDocument:   textDocumentIdentifier{URI: uri},
+		ContentChanges: []textDocumentContentChangeEvent{{Text: "mi klama"}},
+	}
+	if err := s.serve(message(t, "textDocument/didChange", change)); err != nil {
+		t.Fatalf("didChange failed: %v", err)
+	}
+	s.mu.Unlock()
+	s.parses.Wait()
+
+	doc := s.docs[uri]
+	if doc.text != "mi klama" || len(doc.errs) != 0 || len(doc.spans) != 1 {
+		t.Errorf("got document %q with %d spans and errors %v, want %q with 1 span and no errors",
+			doc.text, len(doc.spans), doc.errs, "mi klama")
+	}
+	if len(s.cancels) != 0 {
+		t.Errorf("got %d running parses, want 0", len(s.cancels))
+	}
+	// The first parse may have finished before the change,
+	// but the last diagnostics published are those of the change.
+	published := out.String()
+	i := strings.LastIndex(published, "Content-Length")
+	if want := `"diagnostics":[]`; i < 0 || !strings.Contains(published[i:], want) {
+		t.Errorf("last message published is %q, want it to contain %s", published[i+1:], want)
+	}
+}
+
+func message(t *testing.T, method string, params interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	data, err = json.Marshal(request{JSONRPC: "2.0", Method: method, Params: data})
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+	return data
+}
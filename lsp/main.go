@@ -0,0 +1,374 @@
+// The lsp command is a Language Server Protocol server for Lojban text.
+//
+// It speaks the protocol over standard input and output.
+// It reports syntax errors as diagnostics,
+// shows the grammar rules and selma'o of the word under the cursor on hover,
+// lists the paragraphs and sentences of a document as symbols,
+// and folds quotations and tu'e … tu'u groups.
+//
+// The dialect is set by the -d flag
+// and can be changed by the client with the dialect setting,
+// either in the initialization options or in the johaus section of the configuration:
+//
+//	{"johaus": {"dialect": "ilmentufa"}}
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"within.website/johaus/parser"
+
+	// Register all supported Lojban dialects in init().
+	_ "within.website/johaus/parser/alldialects"
+)
+
+var dialect = flag.String("d", "camxes", "the initial dialect, one of: "+dialectString)
+
+var dialectString = strings.Join(parser.DialectNames(), ", ")
+
+func main() {
+	flag.Parse()
+	if !knownDialect(*dialect) {
+		log.Fatalf("unknown dialect %s, supported dialects are: %s", *dialect, dialectString)
+	}
+	s := &server{
+		in:      bufio.NewReader(os.Stdin),
+		out:     os.Stdout,
+		dialect: *dialect,
+		docs:    make(map[string]*document),
+		cancels: make(map[string]context.CancelFunc),
+	}
+	if err := s.run(); err != nil && err != io.EOF {
+		log.Fatal(err)
+	}
+}
+
+func knownDialect(name string) bool {
+	for _, d := range parser.Dialects() {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	dialect  string
+	docs     map[string]*document
+	shutdown bool
+
+	// mu guards the fields of the server and its documents and writes to out,
+	// which are also used by parses finishing in the background.
+	mu sync.Mutex
+
+	// cancels are the functions canceling the running parses of documents by URI.
+	cancels map[string]context.CancelFunc
+
+	// parses counts the running parses.
+	parses sync.WaitGroup
+}
+
+// errExit is returned by handle for the exit notification.
+var errExit = errors.New("exit")
+
+func (s *server) run() error {
+	for {
+		data, err := s.read()
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		err = s.serve(data)
+		s.mu.Unlock()
+		if err == errExit {
+			if !s.shutdown {
+				os.Exit(1)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// serve handles the message and writes its response, if any.
+// It returns errExit for the exit notification.
+func (s *server) serve(data []byte) error {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return s.reply(nil, nil, &responseError{Code: parseError, Message: err.Error()})
+	}
+	result, rerr := s.handle(&req)
+	if rerr == errExit {
+		return errExit
+	}
+	if req.ID == nil {
+		// Notifications have no response.
+		return nil
+	}
+	var resErr *responseError
+	if rerr != nil {
+		resErr = rerr.(*responseError)
+	}
+	return s.reply(req.ID, result, resErr)
+}
+
+// handle handles a request or notification, returning its result.
+// The error is either errExit or a *responseError.
+func (s *server) handle(req *request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		var params initializeParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		s.configure(params.InitializationOptions)
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:       syncFull,
+				HoverProvider:          true,
+				DocumentSymbolProvider: true,
+				FoldingRangeProvider:   true,
+			},
+			ServerInfo: serverInfo{Name: "johaus"},
+		}, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "exit":
+		return nil, errExit
+
+	case "workspace/didChangeConfiguration":
+		var params didChangeConfigurationParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		var sections struct {
+			Johaus json.RawMessage `json:"johaus"`
+		}
+		if json.Unmarshal(params.Settings, &sections) == nil && sections.Johaus != nil {
+			params.Settings = sections.Johaus
+		}
+		if s.configure(params.Settings) {
+			for _, doc := range s.docs {
+				s.parse(doc)
+			}
+		}
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc := newDocument(params.TextDocument.URI, params.TextDocument.Text)
+		s.docs[doc.uri] = doc
+		s.parse(doc)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var params didChangeTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		// With full synchronization, the last change is the whole text.
+		text := params.ContentChanges[len(params.ContentChanges)-1].Text
+		doc := newDocument(params.TextDocument.URI, text)
+		s.docs[doc.uri] = doc
+		s.parse(doc)
+		return nil, nil
+
+	case "textDocument/didClose":
+		var params didCloseTextDocumentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		if cancel, ok := s.cancels[params.TextDocument.URI]; ok {
+			cancel()
+			delete(s.cancels, params.TextDocument.URI)
+		}
+		delete(s.docs, params.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []diagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		return doc.hover(params.Position), nil
+
+	case "textDocument/documentSymbol":
+		var params documentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return []documentSymbol{}, nil
+		}
+		return doc.symbols(), nil
+
+	case "textDocument/foldingRange":
+		var params documentParams
+		if err := unmarshalParams(req, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return []foldingRange{}, nil
+		}
+		return doc.foldingRanges(), nil
+	}
+
+	if req.ID == nil {
+		// Unknown notifications, such as initialized, are ignored.
+		return nil, nil
+	}
+	return nil, &responseError{Code: methodNotFound, Message: "method not found: " + req.Method}
+}
+
+func unmarshalParams(req *request, params interface{}) error {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		return &responseError{Code: invalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// configure applies the settings, returning whether the dialect changed.
+// An unknown dialect is reported to the client and ignored.
+func (s *server) configure(settings json.RawMessage) bool {
+	var config struct {
+		Dialect string `json:"dialect"`
+	}
+	if len(settings) == 0 || json.Unmarshal(settings, &config) != nil || config.Dialect == "" {
+		return false
+	}
+	if !knownDialect(config.Dialect) {
+		s.notify("window/showMessage", showMessageParams{
+			Type:    messageError,
+			Message: "unknown dialect " + config.Dialect + ", supported dialects are: " + dialectString,
+		})
+		return false
+	}
+	changed := config.Dialect != s.dialect
+	s.dialect = config.Dialect
+	return changed
+}
+
+// parse parses the document in the background,
+// canceling the parse of an earlier version of the document,
+// and publishes its diagnostics when the parse finishes.
+// Until then, the document has no spans or errors.
+// s.mu must be held.
+func (s *server) parse(doc *document) {
+	if cancel, ok := s.cancels[doc.uri]; ok {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancels[doc.uri] = cancel
+	dialect := s.dialect
+	s.parses.Add(1)
+	go func() {
+		defer s.parses.Done()
+		spans, errs := doc.parse(ctx, dialect)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if ctx.Err() != nil {
+			// The document was changed or closed.
+			return
+		}
+		cancel()
+		delete(s.cancels, doc.uri)
+		doc.spans, doc.errs = spans, errs
+		s.publishDiagnostics(doc)
+	}()
+}
+
+func (s *server) publishDiagnostics(doc *document) {
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         doc.uri,
+		Diagnostics: doc.diagnostics(),
+	})
+}
+
+func (s *server) notify(method string, params interface{}) {
+	if err := s.write(notification{JSONRPC: "2.0", Method: method, Params: params}); err != nil {
+		log.Println(err)
+	}
+}
+
+func (s *server) reply(id *json.RawMessage, result interface{}, rerr *responseError) error {
+	resp := response{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		raw := json.RawMessage(data)
+		resp.Result = &raw
+	}
+	return s.write(resp)
+}
+
+// read reads the content of the next message.
+func (s *server) read() ([]byte, error) {
+	header, err := textproto.NewReader(s.in).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("bad Content-Length: %v", err)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(s.in, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// write writes a message with the JSON encoding of v as its content.
+func (s *server) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = s.out.Write(data)
+	return err
+}
+
+func (err *responseError) Error() string { return err.Message }
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/dictionary"
+	"within.website/johaus/gloss"
+	"within.website/johaus/jsontree"
+	"within.website/johaus/logic"
 	"within.website/johaus/parser"
+	"within.website/johaus/parser/morph"
+	"within.website/johaus/places"
 	"within.website/johaus/pretty"
 
 	// Register all supported Lojban dialects in init().
@@ -19,22 +28,57 @@ var (
 	dialect        = flag.String("d", "camxes", "the dialect, one of: "+dialectString)
 	keepMorph      = flag.Bool("m", false, "whether to keep morphology")
 	addTerminators = flag.Bool("t", false, "whether to add elided terminators")
+	errorMode      = flag.String("errors", "word", "the errors to report, one of: word, raw, both")
+	recoverErrors  = flag.Bool("recover", false, "whether to continue parsing at the next sentence after an error")
+	format         = flag.String("format", "text", "the output format, one of: text, json, forest, qtree, syllables, ipa, gloss, places, logic, logic-latex")
+	colorMode      = flag.String("color", "auto", "whether to color the text output, one of: auto, always, never")
+	dictPath       = flag.String("dict", os.Getenv("JOHAUS_DICT"), "the path of the jbovlaste XML export of the gloss and places formats, by default $JOHAUS_DICT")
 )
 
-var dialectString = func() string {
-	var s string
-	for _, d := range parser.Dialects() {
-		if s != "" {
-			s += ", "
-		}
-		s += d.Name
-	}
-	return s
-}()
+var dialectString = strings.Join(parser.DialectNames(), ", ")
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			fmtMain(os.Args[2:])
+			return
+		case "define":
+			defineMain(os.Args[2:])
+			return
+		}
+	}
 	flag.Parse()
 
+	mode, err := parser.ParseErrorMode(*errorMode)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	switch *format {
+	case "text", "json", "forest", "qtree", "syllables", "ipa", "logic", "logic-latex":
+	case "gloss", "places":
+		if *dictPath != "" {
+			if dict, err = dictionary.LoadFile(*dictPath); err != nil {
+				os.Stderr.WriteString(err.Error() + "\n")
+				os.Exit(1)
+			}
+		}
+	default:
+		os.Stderr.WriteString("unknown format: " + *format + "\n")
+		os.Exit(1)
+	}
+	switch *colorMode {
+	case "auto":
+		color = isTerminal(os.Stdout) && os.Getenv("TERM") != "dumb" && os.Getenv("NO_COLOR") == ""
+	case "always":
+		color = true
+	case "never":
+	default:
+		os.Stderr.WriteString("unknown color mode: " + *colorMode + "\n")
+		os.Exit(1)
+	}
+
 	var r io.Reader
 	var filePath string
 	if len(flag.Args()) > 0 {
@@ -56,19 +100,179 @@ func main() {
 	}
 
 	text := string(data)
-	fmt.Println("parsing")
+	opts := parser.Options{Errors: mode}
+	switch *format {
+	case "json":
+		writeJSON(text, filePath, opts)
+		return
+	case "syllables":
+		writeSyllables(text, filePath)
+		return
+	case "ipa":
+		ipa, err := morph.IPA(*dialect, text)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+			os.Exit(1)
+		}
+		fmt.Println(ipa)
+		return
+	}
+	if *recoverErrors {
+		logf("parsing\n")
+		begin := time.Now()
+		spans, err := parser.ParseRecover(context.Background(), *dialect, text, opts)
+		end := time.Now()
+
+		logf("%v\n", end.Sub(begin))
+
+		for _, span := range spans {
+			logf("%d:%d:\n", span.Line, span.Column)
+			printTree(span.Tree)
+		}
+		if errs, ok := err.(parser.ErrorList); ok {
+			for _, err := range errs {
+				printError(err, filePath)
+			}
+			os.Exit(1)
+		}
+		if err != nil {
+			printError(err, filePath)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logf("parsing\n")
 	begin := time.Now()
-	tree, err := parser.Parse(*dialect, text)
+	tree, err := parser.ParseContext(context.Background(), *dialect, text, opts)
 	end := time.Now()
 
-	fmt.Println(end.Sub(begin))
+	logf("%v\n", end.Sub(begin))
 
 	if err != nil {
-		err.(*parser.Error).FilePath = filePath
-		fmt.Println(err)
+		printError(err, filePath)
 		os.Exit(1)
 	}
+	printTree(tree)
+}
 
+// writeJSON parses the text and writes the result in the jsontree encoding.
+func writeJSON(text, filePath string, opts parser.Options) {
+	var res jsontree.Result
+	var err error
+	if *recoverErrors {
+		var spans []parser.Span
+		spans, err = parser.ParseRecover(context.Background(), *dialect, text, opts)
+		index := parser.NewLineIndex(text)
+		for _, span := range spans {
+			// Span trees are parsed from the text of the span alone,
+			// so their positions are offset to positions in the whole text.
+			ranges := parser.Ranges(span.Tree.Text, span.Tree)
+			for n, r := range ranges {
+				ranges[n] = parser.Range{
+					Start: index.Loc(span.Byte + r.Start.Byte),
+					End:   index.Loc(span.Byte + r.End.Byte),
+				}
+			}
+			simplify(span.Tree)
+			res.Spans = append(res.Spans, jsontree.FromTree(span.Tree, ranges))
+		}
+	} else {
+		var tree *peg.Node
+		tree, err = parser.ParseContext(context.Background(), *dialect, text, opts)
+		if err == nil {
+			ranges := parser.Ranges(text, tree)
+			simplify(tree)
+			res.Tree = jsontree.FromTree(tree, ranges)
+		}
+	}
+	if err != nil {
+		var ok bool
+		if res.Errors, ok = jsontree.FromErrors(err); !ok {
+			printError(err, filePath)
+			os.Exit(1)
+		}
+		for _, e := range res.Errors {
+			e.File = filePath
+		}
+	}
+	if err := jsontree.Encode(os.Stdout, &res); err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// writeSyllables writes each word of the text on a line
+// with its class and its syllables, separated by -,
+// with the stressed syllable in capitals, such as:
+//
+//	klama	gismu	KLA-ma
+//
+// The text is split into words without parsing it.
+// Stress errors are printed after the words.
+func writeSyllables(text, filePath string) {
+	ps, err := morph.Pronounce(*dialect, text)
+	if err != nil {
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(1)
+	}
+	var errs []*morph.StressError
+	for _, p := range ps {
+		fmt.Printf("%s\t%s", p.Text, p.Class)
+		for i, s := range p.Syllables {
+			sep := "-"
+			if i == 0 {
+				sep = "\t"
+			}
+			syl := strings.ToLower(s.Text())
+			if i == p.Stress {
+				syl = strings.ToUpper(syl)
+			}
+			fmt.Print(sep + syl)
+		}
+		fmt.Println("")
+		if err, ok := p.Err.(*morph.StressError); ok {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 0 {
+		return
+	}
+	index := parser.NewLineIndex(text)
+	for _, err := range errs {
+		loc := index.Loc(err.Start)
+		fmt.Printf("%s:%d.%d: %s\n", filePath, loc.Line, loc.Column, err)
+	}
+	os.Exit(1)
+}
+
+// logf prints progress messages with the text format.
+// Other formats print only the tree, so that it can be used as is.
+func logf(msg string, args ...interface{}) {
+	if *format == "text" {
+		fmt.Printf(msg, args...)
+	}
+}
+
+func printError(err error, filePath string) {
+	switch err := err.(type) {
+	case *parser.Error:
+		err.FilePath = filePath
+	case *parser.ErrorPair:
+		err.Word.FilePath = filePath
+		err.Raw.FilePath = filePath
+	case *parser.InternalError:
+		peg.PrettyWrite(os.Stderr, err.Tree)
+		os.Stderr.WriteString("\n")
+	}
+	fmt.Println(err)
+}
+
+// simplify applies the simplifications selected by the flags to the tree.
+func simplify(tree *peg.Node) {
 	if !*keepMorph {
 		parser.RemoveMorphology(tree)
 	}
@@ -77,10 +281,67 @@ func main() {
 	}
 	parser.RemoveSpace(tree)
 	parser.CollapseLists(tree)
+}
+
+func printTree(n *peg.Node) {
+	if *format == "gloss" {
+		parser.RemoveMorphology(n)
+		pretty.Interlinear(os.Stdout, gloss.Gloss(*dialect, dict, n))
+		return
+	}
+	if *format == "places" {
+		parser.RemoveMorphology(n)
+		bs, err := places.Assign(n)
+		pretty.Places(os.Stdout, bs, dict)
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	if *format == "logic" || *format == "logic-latex" {
+		parser.RemoveMorphology(n)
+		fs, err := logic.Translate(n)
+		if *format == "logic" {
+			pretty.Logic(os.Stdout, fs)
+		} else {
+			pretty.LogicLaTeX(os.Stdout, fs)
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+	simplify(n)
+
+	switch *format {
+	case "forest", "qtree":
+		opts := pretty.LaTeXOptions{HideMorphology: !*keepMorph, ShowElided: *addTerminators}
+		if *format == "qtree" {
+			opts.Format = pretty.Qtree
+		}
+		pretty.LaTeX(os.Stdout, n, opts)
+		return
+	}
 
-	pretty.Braces(os.Stdout, tree)
+	braces, tree := pretty.Braces, pretty.Tree
+	if color {
+		braces, tree = pretty.ColorBraces, pretty.ColorTree
+	}
+	braces(os.Stdout, n)
 	fmt.Println("")
 
-	pretty.Tree(os.Stdout, tree)
+	tree(os.Stdout, n)
 	fmt.Println("")
 }
+
+// dict is the dictionary of the gloss and places formats, or nil if there is none.
+var dict *dictionary.Dictionary
+
+// color is whether to color the text output.
+var color bool
+
+// isTerminal returns whether the file is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -39,3 +39,19 @@ func (p *_Parser) ParseTree() *peg.Node {
 	_, tree := _textNode(p, 0)
 	return tree
 }
+
+// MemoBytes returns an estimate of the size in bytes of the memo tables
+// allocated to parse a text of n bytes.
+func (p *_Parser) MemoBytes(n int) int {
+	return parser.MemoBytes(_N, n)
+}
+
+// Word returns the position after the Lojban word at the start position
+// and its tree, a lojban_word node,
+// or -1 and nil if there is no Lojban word at the start position.
+func (p *_Parser) Word(start int) (int, *peg.Node) {
+	if pos, _ := _lojban_wordAccepts(p, start); pos < 0 {
+		return -1, nil
+	}
+	return _lojban_wordNode(p, start)
+}
@@ -44,3 +44,12 @@ func Dialects() []Dialect {
 	})
 	return ds
 }
+
+// DialectNames returns the names of all registered dialects in lexical order.
+func DialectNames() []string {
+	var ns []string
+	for _, d := range Dialects() {
+		ns = append(ns, d.Name)
+	}
+	return ns
+}
@@ -17,6 +17,12 @@ type Error struct {
 }
 
 func (err Error) Error() string {
+	return fmt.Sprintf("%s:%d.%d: %s", err.FilePath, err.Line, err.Column, err.Expected())
+}
+
+// Expected returns the description of the error without its location,
+// such as "expected one of: KU, or selbri".
+func (err Error) Expected() string {
 	var want string
 	for i, w := range err.Want {
 		if len(want) > 0 {
@@ -32,13 +38,26 @@ func (err Error) Error() string {
 	} else {
 		want = ": " + want
 	}
-	return fmt.Sprintf("%s:%d.%d: expected %s", err.FilePath, err.Line, err.Column, want)
+	return "expected " + want
+}
+
+// An ErrorPair is returned for a failed parse with the BothErrors mode.
+type ErrorPair struct {
+	// Word is the word-level error.
+	Word *Error
+
+	// Raw is the raw, morphological error.
+	Raw *Error
+}
+
+func (err *ErrorPair) Error() string {
+	return err.Word.Error() + "\nmorphology: " + err.Raw.Error()
 }
 
 // rawError returns an Error from a failed parse tree with the raw, morphological errors.
 // The FilePath on the returned Error is the empty string,
 // but can be set by the caller.
-func rawError(text string, n *peg.Fail) *Error {
+func rawError(index *LineIndex, n *peg.Fail) *Error {
 	fails := getLeaves(n)
 	sort.Slice(fails, func(i, j int) bool {
 		switch a, b := fails[i], fails[j]; {
@@ -61,7 +80,7 @@ func rawError(text string, n *peg.Fail) *Error {
 			wants = append(wants, wantString(f))
 		}
 	}
-	return &Error{Loc: Location(text, max), Want: wants}
+	return &Error{Loc: index.Loc(max), Want: wants}
 }
 
 func getLeaves(n *peg.Fail) []*peg.Fail {
@@ -84,7 +103,7 @@ func getLeaves(n *peg.Fail) []*peg.Fail {
 // wordError returns a word-level Error from a failed parse tree.
 // The FilePath on the returned Error is the empty string,
 // but can be set by the caller.
-func wordError(text string, n *peg.Fail) *Error {
+func wordError(index *LineIndex, n *peg.Fail) *Error {
 	fails, _ := getFails(n)
 	sort.Slice(fails, func(i, j int) bool {
 		switch a, b := fails[i], fails[j]; {
@@ -105,7 +124,7 @@ func wordError(text string, n *peg.Fail) *Error {
 			wants = append(wants, wantString(f))
 		}
 	}
-	return &Error{Loc: Location(text, pos), Want: wants}
+	return &Error{Loc: index.Loc(pos), Want: wants}
 }
 
 func wantString(n *peg.Fail) string {
@@ -13,57 +13,249 @@ type Loc struct {
 	Rune   int
 	Line   int
 	Column int
+
+	// UTF16 is the offset in UTF-16 code units,
+	// as used by JavaScript string indices.
+	UTF16 int
+
+	// UTF16Column is the column in UTF-16 code units,
+	// as used by Language Server Protocol clients.
+	// Like Column, it begins at 1.
+	UTF16Column int
 }
 
 // Location returns the Loc at the corresponding byte offset in the text.
+// To find the Locs of many offsets in the same text, use a LineIndex.
 func Location(text string, byte int) Loc {
-	var loc Loc
-	loc.Line = 1
-	loc.Column = 1
-	for byte > loc.Byte {
-		r, w := utf8.DecodeRuneInString(text[loc.Byte:])
-		loc.Byte += w
-		loc.Rune++
-		loc.Column++
-		if r == '\n' {
-			loc.Line++
-			loc.Column = 1
+	if 0 <= byte && byte < len(text) {
+		// Keep the whole rune at the offset, if it is within one.
+		end := byte + 1
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
 		}
+		text = text[:end]
 	}
-	return loc
+	return NewLineIndex(text).Loc(byte)
 }
 
 // Locations returns a mapping from nodes to their Locs.
 func Locations(text string, n *peg.Fail) map[*peg.Fail]Loc {
-	var loc Loc
-	loc.Line = 1
-	loc.Column = 1
+	index := NewLineIndex(text)
 	locs := make(map[*peg.Fail]Loc)
-	for _, n := range nodesSortedByPos(n) {
-		for n.Pos > loc.Byte {
-			r, w := utf8.DecodeRuneInString(text[loc.Byte:])
-			loc.Byte += w
-			loc.Rune++
-			loc.Column++
-			if r == '\n' {
-				loc.Line++
-				loc.Column = 1
-			}
+	walk(n, func(n *peg.Fail) bool {
+		locs[n] = index.Loc(n.Pos)
+		return true
+	})
+	return locs
+}
+
+// A Range is the range of text spanned by a node.
+type Range struct {
+	// Start is the location of the first byte of the node's text.
+	Start Loc
+
+	// End is the location just after the last byte of the node's text.
+	End Loc
+}
+
+// Ranges returns a mapping from the nodes of a successful parse tree
+// to the Ranges of text they span.
+// The text must be the text that was parsed, and
+// the tree must be the tree returned by the parser,
+// before any nodes are removed from it.
+//
+// RemoveSpace, CollapseLists, RemoveMorphology, and AddElidedTerminators
+// do not create nodes or move text,
+// so the Ranges remain correct for the nodes left in the tree after them.
+func Ranges(text string, n *peg.Node) map[*peg.Node]Range {
+	index := NewLineIndex(text)
+	ranges := make(map[*peg.Node]Range)
+	var visit func(n *peg.Node, byte int)
+	visit = func(n *peg.Node, byte int) {
+		ranges[n] = Range{Start: index.Loc(byte), End: index.Loc(byte + len(n.Text))}
+		for _, k := range n.Kids {
+			visit(k, byte)
+			byte += len(k.Text)
 		}
-		locs[n] = loc
 	}
-	return locs
+	visit(n, 0)
+	return ranges
 }
 
-func nodesSortedByPos(n *peg.Fail) []*peg.Fail {
-	var nodes []*peg.Fail
-	walk(n, func(n *peg.Fail) bool {
-		nodes = append(nodes, n)
-		return true
-	})
-	sort.Slice(nodes, func(i, j int) bool {
-		a, b := nodes[i], nodes[j]
-		return a.Pos == b.Pos && a.Name < b.Name || a.Pos < b.Pos
-	})
-	return nodes
+// A LineIndex converts between byte offsets and Locs in a text.
+// It is built once for a text, in time linear in the size of the text,
+// after which each conversion takes time logarithmic in the size of the text.
+type LineIndex struct {
+	size int
+
+	// lines are the byte offsets of the beginnings of the lines.
+	lines []int
+
+	// wide are the runes encoded in more than one byte, in order.
+	wide []wideRune
+}
+
+// A wideRune is a rune encoded in more than one byte.
+type wideRune struct {
+	// byte, rune, and utf16 are the offsets of the rune
+	// in bytes, runes, and UTF-16 code units.
+	byte, rune, utf16 int
+
+	// size is the size of the rune in bytes.
+	size int
+}
+
+// utf16Len returns the number of UTF-16 code units encoding the rune.
+// Only runes encoded in four bytes of UTF-8 need a surrogate pair.
+func (w wideRune) utf16Len() int {
+	if w.size == utf8.UTFMax {
+		return 2
+	}
+	return 1
+}
+
+// NewLineIndex returns a new LineIndex for the text.
+func NewLineIndex(text string) *LineIndex {
+	index := &LineIndex{size: len(text), lines: []int{0}}
+	var runes, units int
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == '\n' {
+			index.lines = append(index.lines, i+1)
+		}
+		w := wideRune{byte: i, rune: runes, utf16: units, size: size}
+		if size > 1 {
+			index.wide = append(index.wide, w)
+		}
+		i += size
+		runes++
+		units += w.utf16Len()
+	}
+	return index
+}
+
+// Loc returns the Loc at the byte offset.
+// Offsets outside of the text are clamped to its beginning or end,
+// and an offset within a multi-byte rune is moved to the beginning of that rune.
+func (index *LineIndex) Loc(byte int) Loc {
+	byte = index.clamp(byte)
+	if i := index.search(func(w wideRune) bool { return w.byte > byte }); i > 0 {
+		if w := index.wide[i-1]; byte < w.byte+w.size {
+			byte = w.byte
+		}
+	}
+	line := sort.Search(len(index.lines), func(i int) bool { return index.lines[i] > byte }) - 1
+	r, u := index.offsets(byte)
+	lineRune, lineUTF16 := index.offsets(index.lines[line])
+	return Loc{
+		Byte:        byte,
+		Rune:        r,
+		Line:        line + 1,
+		Column:      r - lineRune + 1,
+		UTF16:       u,
+		UTF16Column: u - lineUTF16 + 1,
+	}
+}
+
+// Byte returns the byte offset of the Loc at the line and column.
+// It is the inverse of Loc.
+// Lines outside of the text are clamped to its beginning or end,
+// and columns outside of the line to the beginning or end of the line,
+// the offset of its newline or of the end of the text.
+func (index *LineIndex) Byte(line, column int) int {
+	start, ok := index.lineStart(line)
+	if !ok {
+		return start
+	}
+	if column < 1 {
+		column = 1
+	}
+	r, _ := index.offsets(start)
+	r += column - 1
+	i := index.search(func(w wideRune) bool { return w.rune >= r })
+	if i == 0 {
+		return index.clampLine(line, r)
+	}
+	w := index.wide[i-1]
+	return index.clampLine(line, w.byte+w.size+r-w.rune-1)
+}
+
+// UTF16Byte returns the byte offset of the Loc at the line and UTF-16 column.
+// It is the inverse of Loc.
+// Lines and columns are clamped as by Byte,
+// and a column within a surrogate pair is moved to the beginning of its rune.
+func (index *LineIndex) UTF16Byte(line, utf16Column int) int {
+	start, ok := index.lineStart(line)
+	if !ok {
+		return start
+	}
+	if utf16Column < 1 {
+		utf16Column = 1
+	}
+	_, u := index.offsets(start)
+	u += utf16Column - 1
+	i := index.search(func(w wideRune) bool { return w.utf16 >= u })
+	if i == 0 {
+		return index.clampLine(line, u)
+	}
+	w := index.wide[i-1]
+	if u < w.utf16+w.utf16Len() {
+		return w.byte
+	}
+	return index.clampLine(line, w.byte+w.size+u-w.utf16-w.utf16Len())
+}
+
+// lineStart returns the byte offset of the beginning of the line
+// and whether the line is in the text.
+// If the line is not in the text,
+// the offset is that of the beginning or end of the text.
+func (index *LineIndex) lineStart(line int) (int, bool) {
+	switch {
+	case line < 1:
+		return 0, false
+	case line > len(index.lines):
+		return index.size, false
+	}
+	return index.lines[line-1], true
+}
+
+func (index *LineIndex) clamp(byte int) int {
+	switch {
+	case byte < 0:
+		return 0
+	case byte > index.size:
+		return index.size
+	}
+	return byte
+}
+
+// clampLine clamps the byte offset, at or after the beginning of the line,
+// to the offset of the newline ending the line or of the end of the text.
+func (index *LineIndex) clampLine(line, byte int) int {
+	end := index.size
+	if line < len(index.lines) {
+		end = index.lines[line] - 1
+	}
+	if byte > end {
+		return end
+	}
+	return byte
+}
+
+// search returns the index of the first wide rune for which f is true,
+// or len(index.wide) if there is none.
+func (index *LineIndex) search(f func(wideRune) bool) int {
+	return sort.Search(len(index.wide), func(i int) bool { return f(index.wide[i]) })
+}
+
+// offsets returns the rune and UTF-16 offsets
+// of the byte offset at the beginning of a rune.
+func (index *LineIndex) offsets(byte int) (int, int) {
+	i := index.search(func(w wideRune) bool { return w.byte >= byte })
+	if i == 0 {
+		return byte, byte
+	}
+	w := index.wide[i-1]
+	n := byte - w.byte - w.size
+	return w.rune + 1 + n, w.utf16 + w.utf16Len() + n
 }
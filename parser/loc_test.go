@@ -0,0 +1,183 @@
+package parser_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		text string
+		// words are the line.column ranges of the words, in order.
+		words []string
+	}{
+		{
+			text:  "mi klama",
+			words: []string{"1.1-1.3 mi", "1.4-1.9 klama"},
+		},
+		{
+			text:  "mi\n  klama lo zarci",
+			words: []string{"1.1-1.3 mi", "2.3-2.8 klama", "2.9-2.11 lo", "2.12-2.17 zarci"},
+		},
+		{
+			text:  "mi klama\n\n.i do",
+			words: []string{"1.1-1.3 mi", "1.4-1.9 klama", "3.2-3.3 i", "3.4-3.6 do"},
+		},
+	}
+	for _, test := range tests {
+		tree, err := parser.Parse("camxes", test.text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.text, err)
+			continue
+		}
+		ranges := parser.Ranges(test.text, tree)
+		checkRanges(t, test.text, tree, ranges)
+
+		parser.RemoveMorphology(tree)
+		parser.RemoveSpace(tree)
+		parser.CollapseLists(tree)
+		checkRanges(t, test.text, tree, ranges)
+
+		var words []string
+		var visit func(*peg.Node)
+		visit = func(n *peg.Node) {
+			if parser.IsWord(n) {
+				r := ranges[n]
+				words = append(words, locString(r.Start)+"-"+locString(r.End)+" "+n.Text)
+				return
+			}
+			for _, k := range n.Kids {
+				visit(k)
+			}
+		}
+		visit(tree)
+		if !reflect.DeepEqual(words, test.words) {
+			t.Errorf("Ranges(%q) words=%q, want %q", test.text, words, test.words)
+		}
+	}
+}
+
+// checkRanges checks that each node of the tree has a range spanning its text.
+func checkRanges(t *testing.T, text string, n *peg.Node, ranges map[*peg.Node]parser.Range) {
+	t.Helper()
+	r, ok := ranges[n]
+	if !ok {
+		t.Errorf("Ranges(%q) has no range for %s %q", text, n.Name, n.Text)
+		return
+	}
+	if got := text[r.Start.Byte:r.End.Byte]; got != n.Text {
+		t.Errorf("Ranges(%q) range of %s %q spans %q", text, n.Name, n.Text, got)
+	}
+	for _, k := range n.Kids {
+		checkRanges(t, text, k, ranges)
+	}
+}
+
+func locString(loc parser.Loc) string {
+	return fmt.Sprintf("%d.%d", loc.Line, loc.Column)
+}
+
+func TestLineIndex(t *testing.T) {
+	// é is 2 bytes, 1 UTF-16 code unit,
+	// and 😀 is 4 bytes, 2 UTF-16 code units: a surrogate pair.
+	const text = "ab\ncé😀d\n\nx"
+	tests := []struct {
+		byte int
+		want parser.Loc
+	}{
+		{byte: -1, want: parser.Loc{Byte: 0, Rune: 0, Line: 1, Column: 1, UTF16: 0, UTF16Column: 1}},
+		{byte: 0, want: parser.Loc{Byte: 0, Rune: 0, Line: 1, Column: 1, UTF16: 0, UTF16Column: 1}},
+		{byte: 2, want: parser.Loc{Byte: 2, Rune: 2, Line: 1, Column: 3, UTF16: 2, UTF16Column: 3}},
+		{byte: 3, want: parser.Loc{Byte: 3, Rune: 3, Line: 2, Column: 1, UTF16: 3, UTF16Column: 1}},
+		{byte: 4, want: parser.Loc{Byte: 4, Rune: 4, Line: 2, Column: 2, UTF16: 4, UTF16Column: 2}},
+		{byte: 5, want: parser.Loc{Byte: 4, Rune: 4, Line: 2, Column: 2, UTF16: 4, UTF16Column: 2}},
+		{byte: 6, want: parser.Loc{Byte: 6, Rune: 5, Line: 2, Column: 3, UTF16: 5, UTF16Column: 3}},
+		{byte: 8, want: parser.Loc{Byte: 6, Rune: 5, Line: 2, Column: 3, UTF16: 5, UTF16Column: 3}},
+		{byte: 10, want: parser.Loc{Byte: 10, Rune: 6, Line: 2, Column: 4, UTF16: 7, UTF16Column: 5}},
+		{byte: 11, want: parser.Loc{Byte: 11, Rune: 7, Line: 2, Column: 5, UTF16: 8, UTF16Column: 6}},
+		{byte: 12, want: parser.Loc{Byte: 12, Rune: 8, Line: 3, Column: 1, UTF16: 9, UTF16Column: 1}},
+		{byte: 13, want: parser.Loc{Byte: 13, Rune: 9, Line: 4, Column: 1, UTF16: 10, UTF16Column: 1}},
+		{byte: 14, want: parser.Loc{Byte: 14, Rune: 10, Line: 4, Column: 2, UTF16: 11, UTF16Column: 2}},
+		{byte: 99, want: parser.Loc{Byte: 14, Rune: 10, Line: 4, Column: 2, UTF16: 11, UTF16Column: 2}},
+	}
+	index := parser.NewLineIndex(text)
+	for _, test := range tests {
+		if got := index.Loc(test.byte); got != test.want {
+			t.Errorf("Loc(%d)=%+v, want %+v", test.byte, got, test.want)
+		}
+		if got := parser.Location(text, test.byte); got != test.want {
+			t.Errorf("Location(%q, %d)=%+v, want %+v", text, test.byte, got, test.want)
+		}
+		loc := test.want
+		if got := index.Byte(loc.Line, loc.Column); got != loc.Byte {
+			t.Errorf("Byte(%d, %d)=%d, want %d", loc.Line, loc.Column, got, loc.Byte)
+		}
+		if got := index.UTF16Byte(loc.Line, loc.UTF16Column); got != loc.Byte {
+			t.Errorf("UTF16Byte(%d, %d)=%d, want %d", loc.Line, loc.UTF16Column, got, loc.Byte)
+		}
+	}
+}
+
+func TestLineIndexUTF16Byte(t *testing.T) {
+	const text = "ab\ncé😀d\n\nx"
+	tests := []struct {
+		line, utf16Column int
+		want              int
+	}{
+		{line: 1, utf16Column: 1, want: 0},
+		{line: 2, utf16Column: 2, want: 4},
+		{line: 2, utf16Column: 3, want: 6},
+		// Within the surrogate pair of 😀.
+		{line: 2, utf16Column: 4, want: 6},
+		{line: 2, utf16Column: 5, want: 10},
+		{line: 3, utf16Column: 1, want: 12},
+		{line: 4, utf16Column: 2, want: 14},
+		{line: 4, utf16Column: 9, want: 14},
+		{line: 0, utf16Column: 5, want: 0},
+		{line: 9, utf16Column: 1, want: 14},
+		{line: 2, utf16Column: 0, want: 3},
+		// Past the end of a line, at its newline.
+		{line: 1, utf16Column: 3, want: 2},
+		{line: 1, utf16Column: 9, want: 2},
+		{line: 2, utf16Column: 9, want: 11},
+		{line: 3, utf16Column: 2, want: 12},
+	}
+	index := parser.NewLineIndex(text)
+	for _, test := range tests {
+		if got := index.UTF16Byte(test.line, test.utf16Column); got != test.want {
+			t.Errorf("UTF16Byte(%d, %d)=%d, want %d", test.line, test.utf16Column, got, test.want)
+		}
+	}
+}
+
+func TestLineIndexByte(t *testing.T) {
+	const text = "ab\ncé😀d\n\nx"
+	tests := []struct {
+		line, column int
+		want         int
+	}{
+		{line: 1, column: 1, want: 0},
+		{line: 2, column: 2, want: 4},
+		{line: 2, column: 4, want: 10},
+		{line: 4, column: 9, want: 14},
+		{line: 0, column: 5, want: 0},
+		{line: 9, column: 1, want: 14},
+		{line: 2, column: 0, want: 3},
+		// Past the end of a line, at its newline.
+		{line: 1, column: 3, want: 2},
+		{line: 1, column: 5, want: 2},
+		{line: 2, column: 9, want: 11},
+		{line: 3, column: 2, want: 12},
+	}
+	index := parser.NewLineIndex(text)
+	for _, test := range tests {
+		if got := index.Byte(test.line, test.column); got != test.want {
+			t.Errorf("Byte(%d, %d)=%d, want %d", test.line, test.column, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package morph
+
+import "strings"
+
+// IPA returns a transcription of the text in the International Phonetic Alphabet,
+// such as {ˈkla.ma} for {klama}.
+// Each word is split into syllables and stressed as by Pronounce,
+// with ˈ before its stressed syllable and . between its other syllables.
+// The pause before a word beginning with a vowel,
+// whether or not it is written, is written as the glottal stop ʔ
+// at the beginning of its first syllable,
+// such as {mi ʔo ʔi do} for {mi .o .i do} and {ˈʔa.lis} for {.alis.}.
+// Other pauses, those written with ., ?, or ! and the pause after a cmevla,
+// are written as the minor group break |,
+// such as {la ˈdʒan | ˈkla.ma} for {la djan. klama}.
+// Words are separated by a space,
+// or by a newline if there is a newline between them in the text.
+//
+// The text of zoi and la'o quotations and similar is not Lojban,
+// and is written as it is in the text, between ⟨ and ⟩,
+// as is any other text that is not a Lojban word.
+func IPA(dialect, text string) (string, error) {
+	ps, err := Pronounce(dialect, text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	prev := 0
+	for i, p := range ps {
+		gap := text[prev:p.Start]
+		prev = p.End
+		glottal := p.Syllables != nil && strings.ContainsAny(p.Text[:1], "aeiouyAEIOUY")
+		if i > 0 {
+			pause := strings.ContainsAny(gap, ".?!") || ps[i-1].Class == Cmevla
+			switch {
+			case strings.ContainsAny(gap, "\n"):
+				b.WriteString("\n")
+			case pause && !glottal:
+				b.WriteString(" | ")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(p.ipa(glottal))
+	}
+	return b.String(), nil
+}
+
+// IPA returns a transcription of the word in the International Phonetic Alphabet,
+// without the pauses around it, as by the IPA function.
+func (p Pronunciation) IPA() string {
+	return p.ipa(false)
+}
+
+// ipa returns the transcription of the word,
+// beginning its first syllable with the glottal stop ʔ if glottal is true.
+func (p Pronunciation) ipa(glottal bool) string {
+	if p.Syllables == nil {
+		return "⟨" + p.Text + "⟩"
+	}
+	var b strings.Builder
+	for i, s := range p.Syllables {
+		switch {
+		case i == p.Stress:
+			b.WriteString("ˈ")
+		case i > 0:
+			b.WriteString(".")
+		}
+		if i == 0 && glottal {
+			b.WriteString("ʔ")
+		}
+		onset := strings.ToLower(s.Onset)
+		if n := len(onset); n > 0 && (onset[n-1] == 'i' || onset[n-1] == 'u') {
+			b.WriteString(consonants(onset[:n-1]))
+			b.WriteString(glides[onset[n-1]])
+		} else {
+			b.WriteString(consonants(onset))
+		}
+		nucleus := strings.ToLower(s.Nucleus)
+		switch {
+		case len(nucleus) == 2:
+			b.WriteString(ipaLetters[nucleus[0]])
+			b.WriteString(glides[nucleus[1]])
+		case isConsonant(nucleus[0]):
+			b.WriteString(ipaLetters[nucleus[0]] + "̩")
+		default:
+			b.WriteString(ipaLetters[nucleus[0]])
+		}
+		b.WriteString(consonants(strings.ToLower(s.Coda)))
+	}
+	return b.String()
+}
+
+// consonants returns the transcription of lowercase consonants, ', and h.
+func consonants(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		b.WriteString(ipaLetters[s[i]])
+	}
+	return b.String()
+}
+
+// ipaLetters are the transcriptions of the lowercase letters.
+var ipaLetters = map[byte]string{
+	'a': "a", 'e': "ɛ", 'i': "i", 'o': "o", 'u': "u", 'y': "ə",
+	'b': "b", 'c': "ʃ", 'd': "d", 'f': "f", 'g': "ɡ", 'j': "ʒ", 'k': "k",
+	'l': "l", 'm': "m", 'n': "n", 'p': "p", 'r': "r", 's': "s", 't': "t",
+	'v': "v", 'x': "x", 'z': "z",
+	'\'': "h", 'h': "h",
+}
+
+// glides are the transcriptions of i and u before a vowel
+// and as the second vowel of a diphthong.
+var glides = map[byte]string{'i': "j", 'u': "w"}
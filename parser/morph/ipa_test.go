@@ -0,0 +1,44 @@
+package morph
+
+import "testing"
+
+func TestIPA(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		// Brivla are stressed on their penultimate syllable, and cmavo are not.
+		{text: "mi klama", want: "mi ˈkla.ma"},
+		{text: "mi ba'o klama", want: "mi ba.ho ˈkla.ma"},
+		{text: "ko sipna", want: "ko ˈsip.na"},
+		{text: "coi", want: "ʃoj"},
+		{text: "mi uenzi", want: "mi ˈʔwɛn.zi"},
+		// Marked stress.
+		{text: "mI klama", want: "ˈmi ˈkla.ma"},
+		{text: "klamA", want: "klaˈma"},
+		{text: "KLAMA", want: "ˈkla.ma"},
+		// y, which is never stressed, h for ', and commas between syllables.
+		{text: "mibypli", want: "ˈmi.bə.pli"},
+		{text: "bangrgugde", want: "ban.ɡr̩ˈɡuɡ.dɛ"},
+		{text: "pa,i pai", want: "pa.i paj"},
+		{text: "ba'o bahO", want: "ba.ho baˈho"},
+		// Pauses.
+		{text: "mi .o .i do", want: "mi ʔo ʔi do"},
+		{text: "do. mi? do! mi", want: "do | mi | do | mi"},
+		{text: "la .alis. klama", want: "la ˈʔa.lis | ˈkla.ma"},
+		{text: "la djan. klama", want: "la ˈdʒan | ˈkla.ma"},
+		{text: "la djan. .i", want: "la ˈdʒan ʔi"},
+		{text: "mi klama\n.i do", want: "mi ˈkla.ma\nʔi do"},
+		// The text of quotations is not Lojban.
+		{text: "zoi gy. hello world .gy", want: "zoj ɡə | ⟨hello world⟩ | ɡə"},
+		{text: "zoi gy klama .alis. gy", want: "zoj ɡə ⟨klama .alis⟩ | ɡə"},
+		{text: "la'o dy. Mark Twain .dy", want: "la.ho də | ⟨Mark Twain⟩ | də"},
+		{text: "mi xyzzy", want: "mi ⟨xyzzy⟩"},
+	}
+	for _, test := range tests {
+		got, err := IPA("camxes", test.text)
+		if err != nil || got != test.want {
+			t.Errorf("IPA(%q)=%q, %v, want %q", test.text, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package morph
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// A Rafsi is a rafsi of a lujvo.
+type Rafsi struct {
+	// Text is the rafsi as written in the lujvo,
+	// with ' and not h, and without a hyphen, such as bri.
+	Text string
+
+	// Word is the gismu or cmavo that the rafsi stands for, such as bridi,
+	// or the empty string if the rafsi is not in the RafsiTable.
+	Word string
+
+	// Hyphen is the hyphen following the rafsi in the lujvo:
+	// y, r, n, or the empty string if there is none.
+	Hyphen string
+}
+
+// Jvokaha splits a lujvo into its rafsi and hyphens,
+// such as bri and vla for brivla,
+// and looks up the words that the rafsi stand for in the table.
+// The lujvo may be written with h for '.
+// It is an error if the lujvo is not a single lujvo
+// by the morphology rules of the dialect.
+func (t *RafsiTable) Jvokaha(dialect, lujvo string) ([]Rafsi, error) {
+	lujvo = Normalize(lujvo)
+	words, err := Words(dialect, lujvo)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) != 1 || words[0].Class != Lujvo || words[0].Text != lujvo {
+		return nil, errors.New("not a lujvo: " + lujvo)
+	}
+	rafsi, ok := split(lujvo)
+	if !ok {
+		return nil, errors.New("cannot split lujvo into rafsi: " + lujvo)
+	}
+	for i := range rafsi {
+		rafsi[i].Word, _ = t.Word(rafsi[i].Text)
+	}
+	return rafsi, nil
+}
+
+// split splits a lujvo into rafsi and hyphens.
+// It returns false if the lujvo is not a sequence of rafsi and hyphens.
+func split(lujvo string) ([]Rafsi, bool) {
+	var rafsi []Rafsi
+	for s := lujvo; s != ""; {
+		var n int
+		switch {
+		case len(s) == 5 && isGismu(s):
+			n = 5
+		case len(s) > 4 && s[4] == 'y' && isRafsi4(s[:4]):
+			n = 4
+		case len(s) >= 4 && shape(s[:4]) == "CV'V":
+			n = 4
+		case len(s) >= 3 && isRafsi3(s[:3]):
+			n = 3
+		default:
+			return nil, false
+		}
+		r := Rafsi{Text: s[:n]}
+		s = s[n:]
+		switch {
+		case strings.HasPrefix(s, "y") && !isVowel(r.Text[len(r.Text)-1]):
+			r.Hyphen = "y"
+		case len(rafsi) == 0 && isCVV(r.Text) && len(s) > 1 && s[0] == 'r' && isConsonant(s[1]):
+			r.Hyphen = "r"
+		case len(rafsi) == 0 && isCVV(r.Text) && strings.HasPrefix(s, "nr"):
+			r.Hyphen = "n"
+		}
+		s = s[len(r.Hyphen):]
+		rafsi = append(rafsi, r)
+	}
+	last := rafsi[len(rafsi)-1]
+	if len(rafsi) < 2 || last.Hyphen != "" || !isVowel(last.Text[len(last.Text)-1]) {
+		return nil, false
+	}
+	return rafsi, true
+}
+
+// Jvozba returns the best lujvo for a tanru, a list of gismu and cmavo,
+// such as brivla for bridi valsi,
+// using the rafsi of the words in the table
+// and the four- and five-letter rafsi of gismu.
+// The lujvo is the one with the lowest score
+// by the standard algorithm of The Complete Lojban Language, section 4.12,
+// among those that are a single lujvo by the morphology rules of the dialect
+// and that Jvokaha splits back into the same rafsi.
+func (t *RafsiTable) Jvozba(dialect string, tanru []string) (string, error) {
+	if len(tanru) < 2 {
+		return "", errors.New("a tanru must have at least two words")
+	}
+	choices := make([][]string, len(tanru))
+	for i, w := range tanru {
+		w = Normalize(w)
+		final := i == len(tanru)-1
+		for _, r := range t.Rafsi(w) {
+			if !final || isVowel(r[len(r)-1]) {
+				choices[i] = append(choices[i], r)
+			}
+		}
+		if final && isGismu(w) {
+			choices[i] = append(choices[i], w)
+		}
+		if len(choices[i]) == 0 {
+			return "", errors.New("no rafsi for " + w)
+		}
+	}
+
+	var cands []candidate
+	rafsi := make([]string, len(tanru))
+	var choose func(int)
+	choose = func(i int) {
+		if i == len(rafsi) {
+			cands = append(cands, join(rafsi, false))
+			if shape(rafsi[0]) == "CVC" {
+				// The y-hyphen after an initial CVC rafsi
+				// keeps some lujvo from falling apart into a cmavo and a brivla,
+				// as with tosmabru.
+				cands = append(cands, join(rafsi, true))
+			}
+			return
+		}
+		for _, r := range choices[i] {
+			rafsi[i] = r
+			choose(i + 1)
+		}
+	}
+	choose(0)
+
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].score < cands[j].score })
+	for _, c := range cands {
+		split, err := t.Jvokaha(dialect, c.lujvo)
+		if err != nil || len(split) != len(c.rafsi) {
+			continue
+		}
+		ok := true
+		for i, r := range split {
+			ok = ok && r.Text == c.rafsi[i]
+		}
+		if ok {
+			return c.lujvo, nil
+		}
+	}
+	return "", errors.New("no valid lujvo for " + strings.Join(tanru, " "))
+}
+
+type candidate struct {
+	lujvo string
+	rafsi []string
+	score int
+}
+
+// join joins the rafsi with hyphens, as needed,
+// and with a y-hyphen after the first if tosmabru is set,
+// and returns the lujvo with its score.
+func join(rafsi []string, tosmabru bool) candidate {
+	var b strings.Builder
+	var hyphens int
+	for i, r := range rafsi {
+		b.WriteString(r)
+		if i == len(rafsi)-1 {
+			break
+		}
+		next := rafsi[i+1]
+		var h string
+		switch {
+		case len(r) == 4 && !isVowel(r[3]):
+			h = "y"
+		case i == 0 && isCVV(r) && (len(rafsi) > 2 || shape(next) != "CCV"):
+			h = "r"
+			if next[0] == 'r' {
+				h = "n"
+			}
+		case isVowel(r[len(r)-1]):
+		case i == 0 && tosmabru:
+			h = "y"
+		case !permissible(r[len(r)-1], next[0]):
+			h = "y"
+		case forbiddenTriple(r[len(r)-1:] + next[:2]):
+			h = "y"
+		}
+		if h != "" {
+			hyphens++
+			b.WriteString(h)
+		}
+	}
+	lujvo := b.String()
+
+	// The score of The Complete Lojban Language, section 4.12.
+	l := len(lujvo)
+	a := strings.Count(lujvo, "'")
+	var r int
+	for _, raf := range rafsi {
+		r += shapeScore(raf)
+	}
+	var v int
+	for i := 0; i < len(lujvo); i++ {
+		if isVowel(lujvo[i]) {
+			v++
+		}
+	}
+	return candidate{
+		lujvo: lujvo,
+		rafsi: append([]string(nil), rafsi...),
+		score: 1000*l - 500*a + 100*hyphens - 10*r - v,
+	}
+}
+
+// shapeScore returns the score of the shape of a rafsi.
+func shapeScore(r string) int {
+	switch shape(r) {
+	case "CVCCV":
+		return 1
+	case "CVCC":
+		return 2
+	case "CCVCV":
+		return 3
+	case "CCVC":
+		return 4
+	case "CVC":
+		return 5
+	case "CV'V":
+		return 6
+	case "CCV":
+		return 7
+	case "CVV":
+		return 8
+	}
+	return 0
+}
+
+// shape returns the consonant-vowel shape of the text,
+// such as CVC for bau or CV'V for ma'o.
+func shape(s string) string {
+	b := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		switch {
+		case isVowel(s[i]):
+			b[i] = 'V'
+		case isConsonant(s[i]):
+			b[i] = 'C'
+		default:
+			b[i] = s[i]
+		}
+	}
+	return string(b)
+}
+
+// isRafsi3 returns whether the text has the form of a three-letter rafsi:
+// CVC, CVV with a diphthong, or CCV with a permissible initial pair.
+func isRafsi3(s string) bool {
+	switch shape(s) {
+	case "CVC":
+		return true
+	case "CVV":
+		return isCVV(s)
+	case "CCV":
+		return initialPair(s[0], s[1])
+	}
+	return false
+}
+
+// isRafsi4 returns whether the text has the form of a four-letter rafsi
+// of a gismu: CVCC with a permissible medial pair,
+// or CCVC with a permissible initial pair.
+func isRafsi4(s string) bool {
+	switch shape(s) {
+	case "CVCC":
+		return permissible(s[2], s[3])
+	case "CCVC":
+		return initialPair(s[0], s[1])
+	}
+	return false
+}
+
+// isCVV returns whether the text has the form of a CVV or CV'V rafsi.
+func isCVV(s string) bool {
+	switch shape(s) {
+	case "CV'V":
+		return true
+	case "CVV":
+		switch s[1:] {
+		case "ai", "ei", "oi", "au":
+			return true
+		}
+	}
+	return false
+}
+
+// isGismu returns whether the text has the form of a gismu:
+// CVCCV with a permissible medial pair, or CCVCV with a permissible initial pair.
+func isGismu(s string) bool {
+	switch shape(s) {
+	case "CVCCV":
+		return permissible(s[2], s[3])
+	case "CCVCV":
+		return initialPair(s[0], s[1])
+	}
+	return false
+}
+
+func isVowel(c byte) bool { return strings.IndexByte("aeiou", c) >= 0 }
+
+func isConsonant(c byte) bool { return strings.IndexByte("bcdfgjklmnprstvxz", c) >= 0 }
+
+// permissible returns whether the consonants are a permissible medial pair.
+func permissible(a, b byte) bool {
+	const voiced, unvoiced = "bdgjvz", "cfkpstx"
+	switch {
+	case a == b:
+		return false
+	case strings.IndexByte(voiced, a) >= 0 && strings.IndexByte(unvoiced, b) >= 0,
+		strings.IndexByte(unvoiced, a) >= 0 && strings.IndexByte(voiced, b) >= 0:
+		return false
+	case strings.IndexByte("cjsz", a) >= 0 && strings.IndexByte("cjsz", b) >= 0:
+		return false
+	}
+	switch string([]byte{a, b}) {
+	case "cx", "kx", "xc", "xk", "mz":
+		return false
+	}
+	return true
+}
+
+// initialPair returns whether the consonants are a permissible initial pair.
+func initialPair(a, b byte) bool {
+	switch string([]byte{a, b}) {
+	case "bl", "br",
+		"cf", "ck", "cl", "cm", "cn", "cp", "cr", "ct",
+		"dj", "dr", "dz",
+		"fl", "fr",
+		"gl", "gr",
+		"jb", "jd", "jg", "jm", "jv",
+		"kl", "kr",
+		"ml", "mr",
+		"pl", "pr",
+		"sf", "sk", "sl", "sm", "sn", "sp", "sr", "st",
+		"tc", "tr", "ts",
+		"vl", "vr",
+		"xl", "xr",
+		"zb", "zd", "zg", "zm", "zv":
+		return true
+	}
+	return false
+}
+
+// forbiddenTriple returns whether the consonants are an impermissible medial triple.
+func forbiddenTriple(s string) bool {
+	switch s {
+	case "ndj", "ndz", "ntc", "nts":
+		return true
+	}
+	return false
+}
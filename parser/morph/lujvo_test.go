@@ -0,0 +1,124 @@
+package morph
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func TestJvozba(t *testing.T) {
+	tests := []struct {
+		tanru string
+		want  string
+	}{
+		{tanru: "bridi valsi", want: "brivla"},
+		{tanru: "gerku zdani", want: "gerzda"},
+		{tanru: "BRIDI VALSI", want: "brivla"},
+		// A CCV rafsi scores better than a CVC rafsi.
+		{tanru: "sipna kumfa", want: "snakumfa"},
+		// The last word needs a rafsi ending in a vowel, or the whole gismu.
+		{tanru: "gerku ciska", want: "gerciska"},
+		// y between consonants that are not a permissible pair.
+		{tanru: "mi pilno", want: "mibypli"},
+		// r after an initial CVV rafsi, or n before r.
+		{tanru: "xamgu gasnu", want: "xaurgau"},
+		{tanru: "cmavo gismu", want: "ma'orgi'u"},
+		{tanru: "nanmu remna", want: "naunre'a"},
+		// Without y, mastavla would be the cmavo ma and the brivla stavla.
+		{tanru: "masti tavla", want: "masytavla"},
+		{tanru: "mamta tavla", want: "mamtavla"},
+	}
+	for _, test := range tests {
+		got, err := SampleRafsi.Jvozba("camxes", strings.Fields(test.tanru))
+		if err != nil || got != test.want {
+			t.Errorf("Jvozba(%q)=%q, %v, want %q", test.tanru, got, err, test.want)
+		}
+	}
+}
+
+func TestJvozbaError(t *testing.T) {
+	for _, tanru := range []string{"bridi", "bridi xyzzy", "xyzzy bridi"} {
+		if got, err := SampleRafsi.Jvozba("camxes", strings.Fields(tanru)); err == nil {
+			t.Errorf("Jvozba(%q)=%q, want an error", tanru, got)
+		}
+	}
+}
+
+func TestJvozbaScore(t *testing.T) {
+	// The score is 1000L - 500A + 100H - 10R - V:
+	// the letters, apostrophes, hyphens, rafsi shape scores, and vowels.
+	tests := []struct {
+		rafsi     string
+		tosmabru  bool
+		wantLujvo string
+		wantScore int
+	}{
+		{rafsi: "bri vla", wantLujvo: "brivla", wantScore: 6000 - 10*(7+7) - 2},
+		{rafsi: "ger zda", wantLujvo: "gerzda", wantScore: 6000 - 10*(5+7) - 2},
+		{rafsi: "xau gau", wantLujvo: "xaurgau", wantScore: 7000 + 100 - 10*(8+8) - 4},
+		{rafsi: "nau re'a", wantLujvo: "naunre'a", wantScore: 8000 - 500 + 100 - 10*(8+6) - 4},
+		{rafsi: "mib pli", wantLujvo: "mibypli", wantScore: 7000 + 100 - 10*(5+7) - 2},
+		{rafsi: "klam zda", wantLujvo: "klamyzda", wantScore: 8000 + 100 - 10*(4+7) - 2},
+		{rafsi: "mas tavla", wantLujvo: "mastavla", wantScore: 8000 - 10*(5+1) - 3},
+		{rafsi: "mas tavla", tosmabru: true, wantLujvo: "masytavla", wantScore: 9000 + 100 - 10*(5+1) - 3},
+		{rafsi: "xau gau kla", wantLujvo: "xaurgaukla", wantScore: 10000 + 100 - 10*(8+8+7) - 5},
+	}
+	for _, test := range tests {
+		c := join(strings.Fields(test.rafsi), test.tosmabru)
+		if c.lujvo != test.wantLujvo || c.score != test.wantScore {
+			t.Errorf("join(%q, %v)=%q, %d, want %q, %d",
+				test.rafsi, test.tosmabru, c.lujvo, c.score, test.wantLujvo, test.wantScore)
+		}
+	}
+}
+
+func TestJvokaha(t *testing.T) {
+	tests := []struct {
+		lujvo string
+		want  []Rafsi
+	}{
+		{
+			lujvo: "brivla",
+			want:  []Rafsi{{Text: "bri", Word: "bridi"}, {Text: "vla", Word: "valsi"}},
+		},
+		{
+			lujvo: "BRIVLA",
+			want:  []Rafsi{{Text: "bri", Word: "bridi"}, {Text: "vla", Word: "valsi"}},
+		},
+		{
+			lujvo: "xaurgau",
+			want:  []Rafsi{{Text: "xau", Word: "xamgu", Hyphen: "r"}, {Text: "gau", Word: "gasnu"}},
+		},
+		{
+			lujvo: "naunre'a",
+			want:  []Rafsi{{Text: "nau", Word: "nanmu", Hyphen: "n"}, {Text: "re'a", Word: "remna"}},
+		},
+		{
+			lujvo: "klamyzda",
+			want:  []Rafsi{{Text: "klam", Word: "klama", Hyphen: "y"}, {Text: "zda", Word: "zdani"}},
+		},
+		{
+			lujvo: "gerciska",
+			want:  []Rafsi{{Text: "ger", Word: "gerku"}, {Text: "ciska", Word: "ciska"}},
+		},
+		{
+			// tos is not in the table.
+			lujvo: "tosymabru",
+			want:  []Rafsi{{Text: "tos", Hyphen: "y"}, {Text: "mabru", Word: "mabru"}},
+		},
+		{lujvo: "klama", want: nil},
+		{lujvo: "tosmabru", want: nil},
+		{lujvo: "mi klama", want: nil},
+	}
+	for _, test := range tests {
+		got, err := SampleRafsi.Jvokaha("camxes", test.lujvo)
+		switch {
+		case test.want == nil && err == nil:
+			t.Errorf("Jvokaha(%q)=%+v, want an error", test.lujvo, got)
+		case test.want != nil && (err != nil || !reflect.DeepEqual(got, test.want)):
+			t.Errorf("Jvokaha(%q)=%+v, %v, want %+v", test.lujvo, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// Package morph splits Lojban text into words and classifies them
+// using the morphology rules of a dialect's grammar,
+// without parsing the text grammatically.
+package morph
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+)
+
+// A Class is a morphological class of words.
+type Class int
+
+const (
+	// NonLojban is the class of text that is not a Lojban word,
+	// such as a misspelled word.
+	NonLojban Class = iota
+
+	// Gismu is the class of root brivla, such as klama.
+	Gismu
+
+	// Lujvo is the class of compound brivla, such as brivla.
+	Lujvo
+
+	// Fuhivla is the class of borrowed brivla, such as spageti.
+	Fuhivla
+
+	// Cmevla is the class of names, such as djan.
+	Cmevla
+
+	// Cmavo is the class of structure words, such as lo.
+	Cmavo
+
+	// Foreign is the class of the text of a quotation of non-Lojban text,
+	// such as the text between the delimiters of a zoi quotation.
+	Foreign
+)
+
+var classNames = []string{
+	NonLojban: "non-Lojban",
+	Gismu:     "gismu",
+	Lujvo:     "lujvo",
+	Fuhivla:   "fu'ivla",
+	Cmevla:    "cmevla",
+	Cmavo:     "cmavo",
+	Foreign:   "foreign",
+}
+
+func (c Class) String() string {
+	if c < 0 || int(c) >= len(classNames) {
+		return "Class(" + strconv.Itoa(int(c)) + ")"
+	}
+	return classNames[c]
+}
+
+// A Word is a word of a text.
+type Word struct {
+	// Text is the text of the word as written, without surrounding pauses.
+	Text string
+
+	// Class is the morphological class of the word.
+	Class Class
+
+	// Selmaho is the selma'o of a cmavo, such as KOhA.
+	// It is the empty string for words that are not cmavo
+	// and for cmavo that are in no selma'o of the dialect.
+	Selmaho string
+
+	// Start and End are the byte offsets of the beginning and end of the word.
+	Start, End int
+
+	// Tree is the morphology tree of a Lojban word, a lojban_word node,
+	// or nil if the Class is NonLojban or Foreign.
+	Tree *peg.Node
+}
+
+// Words splits the text into words using the morphology rules of the dialect.
+// Compound cmavo, such as lonu, are split into their cmavo.
+// Words are separated by the parser.SpaceChars, which are not part of any word.
+//
+// Text that is not a Lojban word is a NonLojban word
+// extending to the next of the parser.SpaceChars,
+// so Words succeeds for any text,
+// returning an error only for an unknown dialect.
+//
+// The text quoted by a zoi quotation or similar,
+// such as {zoi gy. any text gy.} or {la'oi text},
+// is a single Foreign word from the beginning of its first word
+// to the end of its last word.
+func Words(dialect, text string) ([]Word, error) {
+	p, err := parser.NewParser(dialect, text)
+	if err != nil {
+		return nil, err
+	}
+	wp, ok := p.(parser.WordParser)
+	if !ok {
+		return nil, errors.New("dialect cannot parse words: " + dialect)
+	}
+	s := splitter{parser: wp, text: text}
+	for s.skipSpace() {
+		w := s.word()
+		s.words = append(s.words, w)
+		switch w.Selmaho {
+		case "ZOI", "MUhOI":
+			// Quotations delimited by a word, such as {zoi gy. text gy.}.
+			s.delimited()
+		case "ZOhOI":
+			// Quotations of a single word, such as {la'oi text}.
+			if s.skipSpace() {
+				start := s.pos
+				s.words = append(s.words, s.foreign(start, s.nonSpace()))
+			}
+		}
+	}
+	return s.words, nil
+}
+
+type splitter struct {
+	parser parser.WordParser
+	text   string
+	pos    int
+	words  []Word
+}
+
+// skipSpace advances past parser.SpaceChars
+// and returns whether there is more text.
+func (s *splitter) skipSpace() bool {
+	for s.pos < len(s.text) && strings.IndexByte(parser.SpaceChars, s.text[s.pos]) >= 0 {
+		s.pos++
+	}
+	return s.pos < len(s.text)
+}
+
+// nonSpace advances to the next of the parser.SpaceChars or the end of the text
+// and returns the new position.
+func (s *splitter) nonSpace() int {
+	for s.pos < len(s.text) && strings.IndexByte(parser.SpaceChars, s.text[s.pos]) < 0 {
+		s.pos++
+	}
+	return s.pos
+}
+
+// word returns the word at the current position and advances past it.
+func (s *splitter) word() Word {
+	start := s.pos
+	end, tree := s.parser.Word(start)
+	if end <= start {
+		s.pos = s.nonSpace()
+		return Word{Text: s.text[start:s.pos], Class: NonLojban, Start: start, End: s.pos}
+	}
+	s.pos = end
+	w := Word{Text: tree.Text, Start: start, End: end, Tree: tree}
+	w.Class, w.Selmaho = classify(tree)
+	return w
+}
+
+// delimited adds the words of a quotation delimited by a word,
+// following its opening cmavo:
+// the delimiter, the Foreign text, and the closing delimiter.
+func (s *splitter) delimited() {
+	if !s.skipSpace() {
+		return
+	}
+	open := s.word()
+	s.words = append(s.words, open)
+	if open.Class == NonLojban {
+		return
+	}
+	delim := strings.ToLower(open.Text)
+	start, end := -1, -1
+	for s.skipSpace() {
+		pos := s.pos
+		if strings.ToLower(s.text[pos:s.nonSpace()]) == delim {
+			s.pos = pos
+			if start >= 0 {
+				s.words = append(s.words, s.foreign(start, end))
+			}
+			s.words = append(s.words, s.word())
+			return
+		}
+		if start < 0 {
+			start = pos
+		}
+		end = s.pos
+	}
+	if start >= 0 {
+		// The quotation is not closed.
+		s.words = append(s.words, s.foreign(start, end))
+	}
+}
+
+func (s *splitter) foreign(start, end int) Word {
+	return Word{Text: s.text[start:end], Class: Foreign, Start: start, End: end}
+}
+
+// classify returns the Class and selma'o of a lojban_word tree.
+func classify(tree *peg.Node) (Class, string) {
+	kind := named(tree)
+	if kind == nil {
+		return NonLojban, ""
+	}
+	n := named(kind)
+	switch kind.Name {
+	case "CMEVLA":
+		return Cmevla, ""
+	case "CMAVO":
+		if n != nil && n.Name != "cmavo" {
+			return Cmavo, n.Name
+		}
+		return Cmavo, ""
+	}
+	if n == nil {
+		return NonLojban, ""
+	}
+	switch strings.TrimRight(n.Name, "_0123456789") {
+	case "gismu":
+		return Gismu, ""
+	case "lujvo":
+		return Lujvo, ""
+	case "fuhivla":
+		return Fuhivla, ""
+	}
+	return NonLojban, ""
+}
+
+// named returns the first named node among the kids of the node,
+// looking through anonymous nodes, or nil if there is none.
+func named(n *peg.Node) *peg.Node {
+	for _, k := range n.Kids {
+		if k.Name != "" {
+			return k
+		}
+		if m := named(k); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// Normalize returns the word in lowercase, with ' and not h,
+// and without the pauses around it, such as klama for .KLAMA.
+func Normalize(word string) string {
+	word = strings.Trim(strings.ToLower(word), parser.SpaceChars)
+	return strings.Replace(word, "h", "'", -1)
+}
@@ -0,0 +1,287 @@
+package morph
+
+import "strings"
+
+// A RafsiTable maps rafsi to the gismu and cmavo they stand for, and back.
+type RafsiTable struct {
+	// words maps each rafsi to its word.
+	words map[string]string
+
+	// rafsi maps each word to its rafsi, in the order they were added.
+	rafsi map[string][]string
+}
+
+// NewRafsiTable returns a new, empty RafsiTable.
+func NewRafsiTable() *RafsiTable {
+	return &RafsiTable{
+		words: make(map[string]string),
+		rafsi: make(map[string][]string),
+	}
+}
+
+// Add adds the three- and four-letter rafsi of the word,
+// written with ' and not h, such as bri for bridi or ma'o for cmavo.
+// The four-letter rafsi of a gismu, such as brid for bridi, need not be given,
+// and a gismu can be added with no rafsi to add only its four-letter rafsi.
+// A rafsi added for more than one word stands for the last.
+func (t *RafsiTable) Add(word string, rafsi ...string) {
+	if isGismu(word) {
+		rafsi = append(rafsi, word[:4])
+	}
+	for _, r := range rafsi {
+		if old, ok := t.words[r]; ok && old != word {
+			t.rafsi[old] = remove(t.rafsi[old], r)
+		}
+		if t.words[r] != word {
+			t.rafsi[word] = append(t.rafsi[word], r)
+		}
+		t.words[r] = word
+	}
+}
+
+func remove(ss []string, s string) []string {
+	var rest []string
+	for _, t := range ss {
+		if t != s {
+			rest = append(rest, t)
+		}
+	}
+	return rest
+}
+
+// Word returns the word that the rafsi stands for,
+// and whether the rafsi is in the table.
+// A five-letter rafsi is a gismu, and stands for itself,
+// whether or not it is in the table.
+func (t *RafsiTable) Word(rafsi string) (string, bool) {
+	if isGismu(rafsi) {
+		return rafsi, true
+	}
+	w, ok := t.words[rafsi]
+	return w, ok
+}
+
+// Rafsi returns the rafsi of the word in the table,
+// not including the full five-letter form of a gismu.
+func (t *RafsiTable) Rafsi(word string) []string {
+	return t.rafsi[word]
+}
+
+// SampleRafsi is a RafsiTable of the rafsi of a sample of about 200 common gismu and cmavo,
+// for examples and tests.
+// It is far from complete, so Jvokaha fails for most lujvo with it
+// and Jvozba finds no rafsi for most words.
+// Use the table of a complete dictionary instead,
+// such as that returned by the RafsiTable method of a dictionary.Dictionary
+// loaded from a jbovlaste export.
+var SampleRafsi = func() *RafsiTable {
+	t := NewRafsiTable()
+	for _, line := range strings.Split(strings.TrimSpace(sampleRafsi), "\n") {
+		fields := strings.Fields(line)
+		t.Add(fields[0], fields[1:]...)
+	}
+	return t
+}()
+
+// sampleRafsi lists words, each followed by its rafsi.
+const sampleRafsi = `
+bangu ban bau
+bartu bar ba'u
+benji bej be'i
+bersa bes be'a
+bilma bil
+blanu bla
+bloti blo lo'i
+bridi bri
+bruna bun
+cabna cab ca'a
+cacra cac
+casnu cas
+cerni cer ce'i
+cfari cfa
+cidja dja
+cinmo cni
+cipni cpi
+ciska cis
+citka cit
+ckaji kai
+ckule kul cu'e
+clani cla
+cmalu cma
+cmavo ma'o
+cmene cme me'e
+cmima cmi
+cortu cor
+cpacu cpa
+crisa cri
+ctuca ctu
+cukta cuk cu'a
+cusku cus cu'u
+danfu daf
+danlu dal
+darno dar da'o
+detri det
+dinju din
+djacu jac ja'u
+djedi dje dei
+djica dji
+djuno jun ju'o
+dotco dot
+dunda dun du'a
+dunli dul du'i
+facki fac
+fanmo fam fa'o
+fengu fen
+finpe fip
+fraso fas
+frica fic fi'a
+fukpi fuk fu'i
+gapru gap ga'u
+gasnu gau gas
+gerku ger ge'u
+gerna gen
+girzu gir gri
+gismu gim gi'u
+glare gla
+glico gli
+gugde gug gu'e
+gunka gun gu'a
+jibni jbi
+jibri jib
+jinvi jiv ji'i
+jmina jmi
+jmive miv ji'e
+jubme jub
+jufra juf ju'a
+jungo jug
+kabri kab
+kakne kak
+kancu kac
+kanla kal
+kanro kan ka'o
+kansa kas
+karce kar
+katna kat ka'a
+kelci kel ke'i
+klama kla
+kukte kuk
+kumfa kum
+ladru lad
+lebna leb le'a
+lenku len
+lerfu ler le'u
+lojbo jbo lob
+lujvo jvo
+lunra lur
+mamta mam ma'a
+mapti map
+masti mas ma'i
+mentu men
+mensi mes
+mikce mic
+minji mij mi'i
+mintu mit mi'u
+mlatu lat
+moklu mol mo'u
+morji mor mo'i
+morsi mro
+mulno mul mu'o
+mupli mup mu'i
+mutce mut
+nanba nab
+nanca nac na'a
+nanmu nau
+nelci nel
+nicte nic ni'e
+ninmu nim ni'u
+papri pap
+patfu paf
+pelxu pel pe'u
+pendo pen pe'o
+pensi pes pe'i
+pilno pli pi'o
+pixra pix pi'a
+pleji ple
+ponjo pon
+prami pam pa'i
+prenu pre
+pritu pri
+punji puj
+rafsi raf ra'i
+rectu rec re'u
+remna rem re'a
+ricfu rif ri'u
+rinka rik
+rirni rir ri'i
+rusko rus
+sipna sip sna
+sisku sis
+skami sam
+skari ska
+snidu sni
+snura snu
+solri sol
+sonci son so'i
+speni spe
+spuda spu
+srana sra
+srera sre
+sruri rur ru'i
+stedu sed se'u
+stizu tiz
+sumti sum su'i
+sutra sut su'a
+tadni tad
+tarci tar
+tavla tav
+tcadu tca
+tcidu tid
+terpa tep te'a
+titla tit ti'a
+tixnu tix ti'u
+tordu tor to'u
+troci toc tro to'i
+tsani tsa
+tuple tup tu'e
+tutci tci
+valsi val vla
+vecnu ven ve'u
+verba ver
+viska vis vi'a
+vorme vro
+xamgu xag xau
+xance xan xa'e
+xebni xen xe'i
+xekri xek
+xirma xir xi'a
+xunre xun xu'e
+zbasu zba
+zdani zda
+zdile zdi
+zgana zga
+zukte zuk zu'e
+bi biv
+ci cib
+du'u dum
+ka kam
+la lan
+mi mib
+mu mum
+na nar
+na'e nal
+ni nil
+no non
+nu nun
+pa pav
+re rel
+ro rol ro'o
+se sel
+so sos
+te ter
+to'e tol to'e
+ve vel
+vo von
+xa xav
+xe xel
+ze zel
+`
@@ -0,0 +1,348 @@
+package morph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Syllable is a syllable of a word.
+type Syllable struct {
+	// Onset, Nucleus, and Coda are the parts of the syllable as written.
+	// The onset is the consonants, ' or h, and the glide i or u
+	// before the nucleus, such as kl for kla.
+	// The nucleus is a vowel, a diphthong such as ai,
+	// or, in a syllable with no vowel, a syllabic consonant l, m, n, or r,
+	// such as the r of bangrgugde.
+	// The coda is the consonants after the nucleus.
+	Onset, Nucleus, Coda string
+
+	// Start and End are the byte offsets of the beginning and end of the syllable.
+	// Commas between syllables are not part of any syllable.
+	Start, End int
+}
+
+// Text returns the text of the syllable as written.
+func (s Syllable) Text() string { return s.Onset + s.Nucleus + s.Coda }
+
+// Stressable returns whether the syllable can be stressed:
+// whether its nucleus is neither y nor a syllabic consonant.
+func (s Syllable) Stressable() bool {
+	return s.Nucleus != "" && isVowel(lower(s.Nucleus[0]))
+}
+
+// Syllables splits a Lojban word into its syllables,
+// with byte offsets from the beginning of the word.
+// Consonants between vowels begin the second syllable
+// if they can begin a word, as by the grammar's onset rule,
+// so klama is kla ma, and gismu is gi smu.
+// ' and h, and commas, separate syllables.
+//
+// Syllables returns nil if the word has a letter that is not in the Lojban alphabet.
+func Syllables(word string) []Syllable {
+	lw := make([]byte, len(word))
+	for i := 0; i < len(word); i++ {
+		c := lower(word[i])
+		if c == 'h' {
+			c = '\''
+		}
+		if !isVowel(c) && c != 'y' && !isConsonant(c) && c != '\'' && c != ',' {
+			return nil
+		}
+		lw[i] = c
+	}
+
+	var syls []Syllable
+	start := 0
+	for _, nuc := range nuclei(lw) {
+		var syl Syllable
+		if len(syls) == 0 {
+			syl.Start = skipCommas(lw, 0)
+		} else {
+			prev := &syls[len(syls)-1]
+			coda := onsetStart(lw, start, nuc[0])
+			end := coda
+			for end > prev.End && lw[end-1] == ',' {
+				end--
+			}
+			prev.Coda = word[prev.End:end]
+			prev.End = end
+			syl.Start = skipCommas(lw, coda)
+		}
+		syl.Onset = word[syl.Start:nuc[0]]
+		syl.Nucleus = word[nuc[0]:nuc[1]]
+		syl.End = nuc[1]
+		syls = append(syls, syl)
+		start = nuc[1]
+	}
+	if len(syls) > 0 {
+		last := &syls[len(syls)-1]
+		end := len(word)
+		for end > last.End && lw[end-1] == ',' {
+			end--
+		}
+		last.Coda = word[last.End:end]
+		last.End = end
+	}
+	return syls
+}
+
+// nuclei returns the byte offsets of the beginning and end of each nucleus
+// of the lowercase word.
+func nuclei(lw []byte) [][2]int {
+	var ns [][2]int
+	for i := 0; i < len(lw); i++ {
+		c := lw[i]
+		switch {
+		case c == 'y':
+			ns = append(ns, [2]int{i, i + 1})
+		case isVowel(c):
+			if (c == 'i' || c == 'u') && i+1 < len(lw) && isVowel(lw[i+1]) {
+				// A glide, in the onset of the next nucleus.
+				continue
+			}
+			if i+1 < len(lw) && isDiphthong(c, lw[i+1]) && (i+2 == len(lw) || !isVowel(lw[i+2]) && lw[i+2] != 'y') {
+				ns = append(ns, [2]int{i, i + 2})
+				i++
+				continue
+			}
+			ns = append(ns, [2]int{i, i + 1})
+		case isSyllabic(lw, i):
+			ns = append(ns, [2]int{i, i + 1})
+		}
+	}
+	return ns
+}
+
+// isDiphthong returns whether the vowels are one of the diphthongs ai, ei, oi, or au.
+func isDiphthong(a, b byte) bool {
+	return b == 'i' && (a == 'a' || a == 'e' || a == 'o') || a == 'a' && b == 'u'
+}
+
+// isSyllabic returns whether the consonant at i of the lowercase word
+// is a syllabic l, m, n, or r, the nucleus of a syllable with no vowel:
+// one between consonants that does not begin a cluster with the consonant after it,
+// as the r of bangrgugde.
+func isSyllabic(lw []byte, i int) bool {
+	if strings.IndexByte("lmnr", lw[i]) < 0 || i == 0 || i+1 == len(lw) {
+		return false
+	}
+	return isConsonant(lw[i-1]) && isConsonant(lw[i+1]) && !initialPair(lw[i], lw[i+1])
+}
+
+// onsetStart returns the byte offset of the beginning of the onset
+// of the nucleus beginning at end, after the nucleus ending at start.
+func onsetStart(lw []byte, start, end int) int {
+	for i := end - 1; i >= start; i-- {
+		if lw[i] == ',' || lw[i] == '\'' {
+			if lw[i] == ',' {
+				i++
+			}
+			return i
+		}
+	}
+	// The glide before the nucleus is in the onset.
+	cons := end
+	for cons > start && (lw[cons-1] == 'i' || lw[cons-1] == 'u') {
+		cons--
+	}
+	// The onset has the longest run of consonants that can begin a word.
+	on := cons
+	for on > start && isInitial(string(lw[on-1:cons])) {
+		on--
+	}
+	return on
+}
+
+func skipCommas(lw []byte, i int) int {
+	for i < len(lw) && lw[i] == ',' {
+		i++
+	}
+	return i
+}
+
+// isInitial returns whether the lowercase consonants can begin a word,
+// as by the grammar's initial rule:
+// an affricate, such as tc, or an optional sibilant, other consonant, and liquid,
+// such as s, sm, sml, or ml.
+func isInitial(s string) bool {
+	switch s {
+	case "tc", "ts", "dj", "dz":
+		return true
+	}
+	next := func(i int) byte {
+		if i+1 < len(s) {
+			return s[i+1]
+		}
+		return 0
+	}
+	i := 0
+	if i < len(s) {
+		switch c, n := s[i], next(i); {
+		case c == 'c', c == 's' && n != 'x', (c == 'j' || c == 'z') && n != 'n' && n != 'l' && n != 'r':
+			i++
+		}
+	}
+	if i < len(s) {
+		switch c, n := s[i], next(i); {
+		case strings.IndexByte("pkfxbgvm", c) >= 0,
+			(c == 't' || c == 'd') && n != 'l',
+			c == 'n' && n != 'l' && n != 'r':
+			i++
+		}
+	}
+	if i < len(s) && (s[i] == 'l' || s[i] == 'r') {
+		i++
+	}
+	return i > 0 && i == len(s)
+}
+
+func lower(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// A Pronunciation is a word with its syllables and stress.
+type Pronunciation struct {
+	Word
+
+	// Syllables are the syllables of the word,
+	// with byte offsets from the beginning of the text,
+	// or nil if the word is Foreign or NonLojban.
+	// A NonLojban word that would be a brivla but for its stress
+	// has its syllables and a StressError.
+	Syllables []Syllable
+
+	// Stress is the index of the stressed syllable,
+	// or -1 if no syllable is stressed, as for most cmavo.
+	Stress int
+
+	// Marked is whether the stress is marked with capital letters,
+	// such as klAma or djAN.
+	Marked bool
+
+	// Err is a *StressError if the stress marked on the word is wrong,
+	// or nil.
+	Err error
+}
+
+// A StressError is an error in the stress marked on a word with capital letters.
+type StressError struct {
+	// Word is the word as written.
+	Word string
+
+	// Start is the byte offset of the beginning of the word.
+	Start int
+
+	// Msg describes the error.
+	Msg string
+}
+
+func (err *StressError) Error() string {
+	return fmt.Sprintf("stress error: %s: %s", err.Word, err.Msg)
+}
+
+// Pronounce splits the text into words, as by Words,
+// and returns the syllables and stressed syllable of each.
+//
+// Brivla are stressed on their penultimate syllable,
+// not counting syllables with y or a syllabic consonant,
+// as are cmevla, unless another syllable is marked.
+// Cmavo are not stressed, unless a syllable is marked.
+// A syllable is marked by a capital vowel, such as klAma,
+// but a word in all capitals, such as DJAN, is not marked.
+//
+// A word with stress marked on more than one syllable
+// or on a syllable that cannot be stressed, such as mY,
+// or a brivla with stress marked other than on its penultimate syllable,
+// such as klamA, has a StressError.
+// The grammar does not allow such a brivla, so its Class is NonLojban.
+func Pronounce(dialect, text string) ([]Pronunciation, error) {
+	words, err := Words(dialect, text)
+	if err != nil {
+		return nil, err
+	}
+	ps := make([]Pronunciation, len(words))
+	for i, w := range words {
+		ps[i] = Pronunciation{Word: w, Stress: -1}
+		p := &ps[i]
+		class := w.Class
+		if class == NonLojban && strings.ToLower(w.Text) != w.Text {
+			class = unstressedClass(dialect, w.Text)
+		}
+		if class == NonLojban || class == Foreign {
+			continue
+		}
+		p.Syllables = Syllables(w.Text)
+		for j := range p.Syllables {
+			p.Syllables[j].Start += w.Start
+			p.Syllables[j].End += w.Start
+		}
+		p.stress(class)
+	}
+	return ps, nil
+}
+
+// unstressedClass returns the Class of the word written in lowercase,
+// if it is a single brivla, and otherwise NonLojban.
+func unstressedClass(dialect, word string) Class {
+	lw := strings.ToLower(word)
+	words, err := Words(dialect, lw)
+	if err != nil || len(words) != 1 || words[0].Text != lw {
+		return NonLojban
+	}
+	switch c := words[0].Class; c {
+	case Gismu, Lujvo, Fuhivla:
+		return c
+	}
+	return NonLojban
+}
+
+// stress sets the stress of the pronunciation of a word of the class
+// and reports errors in its marked stress.
+func (p *Pronunciation) stress(class Class) {
+	var marked []int
+	var stressable []int
+	for i, s := range p.Syllables {
+		if strings.ToLower(s.Nucleus) != s.Nucleus {
+			marked = append(marked, i)
+		}
+		if s.Stressable() {
+			stressable = append(stressable, i)
+		}
+	}
+	brivla := class == Gismu || class == Lujvo || class == Fuhivla
+	if p.Text == strings.ToUpper(p.Text) {
+		// A word in all capitals.
+		marked = nil
+	}
+
+	penult := -1
+	if n := len(stressable); n >= 2 {
+		penult = stressable[n-2]
+	} else if n == 1 {
+		penult = stressable[0]
+	}
+	if len(marked) == 0 {
+		if class != Cmavo {
+			p.Stress = penult
+		}
+		return
+	}
+
+	p.Stress, p.Marked = marked[0], true
+	switch s := p.Syllables[p.Stress]; {
+	case len(marked) > 1:
+		p.fail("stress is marked on more than one syllable")
+	case !s.Stressable():
+		p.fail(fmt.Sprintf("stress is marked on %q, which has no vowel that can be stressed", s.Text()))
+	case brivla && p.Stress != penult && penult >= 0:
+		p.fail(fmt.Sprintf("stress is marked on %q, but a brivla is stressed on its penultimate syllable, %q",
+			s.Text(), p.Syllables[penult].Text()))
+	}
+}
+
+func (p *Pronunciation) fail(msg string) {
+	p.Err = &StressError{Word: p.Text, Start: p.Start, Msg: msg}
+}
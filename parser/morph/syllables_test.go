@@ -0,0 +1,163 @@
+package morph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSyllables(t *testing.T) {
+	tests := []struct {
+		word string
+		// want is the text of each syllable, separated by spaces.
+		want string
+	}{
+		{word: "mi", want: "mi"},
+		{word: "klama", want: "kla ma"},
+		{word: "gismu", want: "gi smu"},
+		{word: "lojbo", want: "lo jbo"},
+		{word: "tcaci", want: "tca ci"},
+		{word: "bangrgugde", want: "ban gr gug de"},
+		{word: "mibypli", want: "mi by pli"},
+		{word: "uenzi", want: "uen zi"},
+		{word: "ba'o", want: "ba 'o"},
+		{word: "bahO", want: "ba hO"},
+		{word: "pai", want: "pai"},
+		{word: "pa,i", want: "pa i"},
+		{word: "djAn", want: "djAn"},
+		{word: "KLAMA", want: "KLA MA"},
+		{word: "wow", want: ""},
+	}
+	for _, test := range tests {
+		var got []string
+		for _, s := range Syllables(test.word) {
+			got = append(got, s.Text())
+			if s.Text() != test.word[s.Start:s.End] {
+				t.Errorf("Syllables(%q) has %q at [%d:%d]=%q", test.word, s.Text(), s.Start, s.End, test.word[s.Start:s.End])
+			}
+		}
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("Syllables(%q)=%q, want %q", test.word, strings.Join(got, " "), test.want)
+		}
+	}
+}
+
+func TestSyllablesCommas(t *testing.T) {
+	// Commas are not part of any syllable.
+	want := []Syllable{
+		{Onset: "p", Nucleus: "a", Start: 1, End: 3},
+		{Nucleus: "i", Start: 4, End: 5},
+	}
+	got := Syllables(",pa,i,")
+	if len(got) != len(want) {
+		t.Fatalf("Syllables(%q)=%+v, want %+v", ",pa,i,", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Syllables(%q)[%d]=%+v, want %+v", ",pa,i,", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPronounce(t *testing.T) {
+	tests := []struct {
+		text string
+		// want is the syllables of each word separated by dots,
+		// with ˈ before the stressed syllable
+		// and ! after a word with marked stress.
+		want string
+	}{
+		{text: "mi klama", want: "mi ˈkla.ma"},
+		{text: "lo bangrgugde", want: "lo ban.gr.ˈgug.de"},
+		{text: "mibypli", want: "ˈmi.by.pli"},
+		{text: "la .djan.", want: "la ˈdjan"},
+		{text: "la .alis.", want: "la ˈa.lis"},
+		// Capital vowels mark the stress.
+		{text: "klAma", want: "ˈklA.ma!"},
+		{text: "la .djAn.", want: "la ˈdjAn!"},
+		{text: "la .Alis.", want: "la ˈA.lis!"},
+		{text: "la .alIs.", want: "la a.ˈlIs!"},
+		{text: "ba'E", want: "ba.ˈ'E!"},
+		// A word in all capitals is not marked,
+		// but a brivla in all capitals is stressed as usual.
+		{text: "KLAMA", want: "ˈKLA.MA"},
+		{text: "LA .DJAN.", want: "LA ˈDJAN"},
+	}
+	for _, test := range tests {
+		ps, err := Pronounce("camxes", test.text)
+		if err != nil {
+			t.Errorf("Pronounce(%q) failed: %v", test.text, err)
+			continue
+		}
+		var words []string
+		for _, p := range ps {
+			if p.Err != nil {
+				t.Errorf("Pronounce(%q): %v", test.text, p.Err)
+			}
+			var syls []string
+			for i, s := range p.Syllables {
+				if i == p.Stress {
+					syls = append(syls, "ˈ"+s.Text())
+				} else {
+					syls = append(syls, s.Text())
+				}
+			}
+			w := strings.Join(syls, ".")
+			if p.Marked {
+				w += "!"
+			}
+			words = append(words, w)
+		}
+		if got := strings.Join(words, " "); got != test.want {
+			t.Errorf("Pronounce(%q)=%q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestStressError(t *testing.T) {
+	tests := []struct {
+		text  string
+		word  string
+		start int
+		msg   string
+	}{
+		{
+			text:  "mi klamA",
+			word:  "klamA",
+			start: 3,
+			msg:   `stress is marked on "mA", but a brivla is stressed on its penultimate syllable, "kla"`,
+		},
+		{
+			text:  "klAmA",
+			word:  "klAmA",
+			start: 0,
+			msg:   "stress is marked on more than one syllable",
+		},
+		{
+			text:  "lo mibYpli",
+			word:  "mibYpli",
+			start: 3,
+			msg:   `stress is marked on "bY", which has no vowel that can be stressed`,
+		},
+	}
+	for _, test := range tests {
+		ps, err := Pronounce("camxes", test.text)
+		if err != nil {
+			t.Errorf("Pronounce(%q) failed: %v", test.text, err)
+			continue
+		}
+		var serr *StressError
+		for _, p := range ps {
+			if p.Err != nil {
+				serr = p.Err.(*StressError)
+			}
+		}
+		if serr == nil {
+			t.Errorf("Pronounce(%q) has no StressError", test.text)
+			continue
+		}
+		if serr.Word != test.word || serr.Start != test.start || serr.Msg != test.msg {
+			t.Errorf("Pronounce(%q) error=%+v, want {Word:%s Start:%d Msg:%s}",
+				test.text, *serr, test.word, test.start, test.msg)
+		}
+	}
+}
@@ -2,9 +2,10 @@
 package parser
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
+	"strconv"
 
 	"github.com/eaburns/peggy/peg"
 )
@@ -24,26 +25,237 @@ type Parser interface {
 	ParseTree() *peg.Node
 }
 
+// A WordParser is a Parser that can also parse single words of the text,
+// using the morphology rules of the dialect's grammar,
+// without parsing the whole text.
+type WordParser interface {
+	Parser
+
+	// Word returns the position after the Lojban word at the start position
+	// and its tree, a lojban_word node,
+	// or -1 and nil if there is no Lojban word at the start position.
+	Word(start int) (int, *peg.Node)
+}
+
+// NewParser returns a low-level Parser for the text using a given Lojban dialect.
+// The Parsers of all dialects in this module are also WordParsers.
+func NewParser(dialect string, text string) (Parser, error) {
+	makeParser, ok := makeParserFuncs[dialect]
+	if !ok {
+		return nil, errors.New("unknown dialect: " + dialect)
+	}
+	return makeParser(text), nil
+}
+
+// A memoSizer is a Parser that can report the size of its memo tables.
+type memoSizer interface {
+	// MemoBytes returns an estimate of the size in bytes of the memo tables
+	// allocated to parse a text of n bytes.
+	MemoBytes(n int) int
+}
+
+// MemoBytes returns an estimate of the size in bytes of the memo tables
+// allocated by a peggy parser of a grammar with the number of rules
+// to parse a text of n bytes:
+// a deltaPos and a deltaErr int32 for each rule at each position,
+// and the entries of the table of the nodes of the parse tree,
+// or of the fails of the error tree of a failed parse,
+// with their nodes or fails.
+// Parsing Lojban text memoizes about ten nodes or fails for each byte,
+// each entry taking about 128 bytes.
+func MemoBytes(rules, n int) int {
+	const (
+		deltaBytes     = 2 * 4
+		entriesPerByte = 10
+		entryBytes     = 128
+	)
+	return (deltaBytes*rules + entriesPerByte*entryBytes) * (n + 1)
+}
+
+// Options are options for ParseContext.
+//
+// A parse is interrupted only between its phases:
+// if ParseContext returns because its context is done,
+// the parse continues in the background until its current phase finishes.
+// The limits bound the time and memory that such a parse can use.
+type Options struct {
+	// MaxBytes is the maximum size of the text in bytes.
+	// If MaxBytes is 0, the size of the text is not limited.
+	MaxBytes int
+
+	// MaxMemoBytes is the maximum estimated size in bytes of the parser's memo tables.
+	// The memo tables grow with the size of the text
+	// and with the number of rules in the dialect's grammar.
+	// If MaxMemoBytes is 0, the size of the memo tables is not limited.
+	MaxMemoBytes int
+
+	// Errors selects the errors returned for a failed parse.
+	Errors ErrorMode
+}
+
+// An ErrorMode selects the errors returned for a failed parse.
+type ErrorMode int
+
+const (
+	// WordErrors reports the words expected at the error location.
+	// This is the default.
+	WordErrors ErrorMode = iota
+
+	// RawErrors reports the deepest morphological errors,
+	// such as an invalid consonant cluster or a missing stress.
+	RawErrors
+
+	// BothErrors reports both the word-level and the raw, morphological errors
+	// as an *ErrorPair.
+	BothErrors
+)
+
+var errorModeNames = []string{
+	WordErrors: "word",
+	RawErrors:  "raw",
+	BothErrors: "both",
+}
+
+func (m ErrorMode) String() string {
+	if m < 0 || int(m) >= len(errorModeNames) {
+		return "ErrorMode(" + strconv.Itoa(int(m)) + ")"
+	}
+	return errorModeNames[m]
+}
+
+// ParseErrorMode returns the ErrorMode with the given name: word, raw, or both.
+func ParseErrorMode(name string) (ErrorMode, error) {
+	for m, n := range errorModeNames {
+		if n == name {
+			return ErrorMode(m), nil
+		}
+	}
+	return 0, errors.New("unknown error mode: " + name)
+}
+
+// A BudgetError is returned by ParseContext
+// when parsing the text would exceed a limit set in the Options.
+type BudgetError struct {
+	// Budget is the name of the exceeded Options field.
+	Budget string
+
+	// Limit is the value of the exceeded Options field.
+	Limit int
+
+	// Size is the size that exceeds the limit.
+	Size int
+}
+
+func (err *BudgetError) Error() string {
+	return fmt.Sprintf("%s exceeded: %d > %d", err.Budget, err.Size, err.Limit)
+}
+
 // Parse parses text using a given Lojban dialect.
 // On success, the parseTree is returned.
-// On failure, both the word-level and the raw, morphological errors are returned.
+// On failure, the word-level error is returned.
 func Parse(dialect string, text string) (*peg.Node, error) {
+	return ParseContext(context.Background(), dialect, text, Options{})
+}
+
+// ParseContext is like Parse, but it stops waiting for the parse
+// when the context is done, returning the context's error,
+// and it refuses to parse texts that exceed the limits in opts,
+// returning a *BudgetError.
+//
+// The parser checks the context only between the phases of a parse:
+// parsing the text, building its tree, and building its error trees.
+// A parse abandoned because of the context continues in the background
+// until its current phase finishes, and then releases its memo tables.
+// Each phase runs in time linear in the size of the text,
+// so the limits in opts also bound how long an abandoned parse can run.
+func ParseContext(ctx context.Context, dialect string, text string, opts Options) (*peg.Node, error) {
 	makeParser, ok := makeParserFuncs[dialect]
 	if !ok {
 		return nil, errors.New("unknown dialect: " + dialect)
 	}
-	p := makeParser(text)
-	if perr, ok := p.Parse(); !ok {
-		errTree := p.ErrorTree(perr)
-		word := errorWordStart(errTree)
-		if word < 0 {
-			// BUG: {ji gi'e} with -d=maftufa returns a bad tree.
-			fmt.Println("perr:", perr, "word err:", word)
-			peg.PrettyWrite(os.Stdout, errTree)
-			panic("")
+	if opts.MaxBytes > 0 && len(text) > opts.MaxBytes {
+		return nil, &BudgetError{Budget: "MaxBytes", Limit: opts.MaxBytes, Size: len(text)}
+	}
+	if opts.MaxMemoBytes > 0 {
+		if s, ok := makeParser("").(memoSizer); ok {
+			if n := s.MemoBytes(len(text)); n > opts.MaxMemoBytes {
+				return nil, &BudgetError{Budget: "MaxMemoBytes", Limit: opts.MaxMemoBytes, Size: n}
+			}
 		}
-		errTree = p.ErrorTree(word)
-		return nil, wordError(text, errTree)
 	}
-	return p.ParseTree(), nil
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		// The context can never be canceled.
+		return parse(ctx, dialect, makeParser(text), text, opts.Errors)
+	}
+	type result struct {
+		tree *peg.Node
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		tree, err := parse(ctx, dialect, makeParser(text), text, opts.Errors)
+		ch <- result{tree: tree, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		return r.tree, r.err
+	}
+}
+
+// parse parses the text with p,
+// returning the context's error if it is done after a phase of the parse.
+func parse(ctx context.Context, dialect string, p Parser, text string, mode ErrorMode) (*peg.Node, error) {
+	perr, ok := p.Parse()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ok {
+		return p.ParseTree(), nil
+	}
+	errTree := p.ErrorTree(perr)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	index := NewLineIndex(text)
+	raw := rawError(index, errTree)
+	word := errorWordStart(errTree)
+	if word < 0 {
+		// There is no word-level error, for example {ji gi'e} with -d=maftufa,
+		// so fall back to the raw, morphological error.
+		if len(raw.Want) > 0 {
+			return nil, raw
+		}
+		return nil, &InternalError{Dialect: dialect, Text: text, Tree: errTree}
+	}
+	switch mode {
+	case RawErrors:
+		return nil, raw
+	case BothErrors:
+		return nil, &ErrorPair{Word: wordError(index, p.ErrorTree(word)), Raw: raw}
+	default:
+		return nil, wordError(index, p.ErrorTree(word))
+	}
+}
+
+// An InternalError is returned for a failed parse
+// whose error tree cannot be reported as an Error.
+// It indicates a bug in the dialect's grammar or in this package.
+type InternalError struct {
+	// Dialect is the name of the dialect.
+	Dialect string
+
+	// Text is the text that failed to parse.
+	Text string
+
+	// Tree is the parse error tree.
+	Tree *peg.Fail
+}
+
+func (err *InternalError) Error() string {
+	return fmt.Sprintf("internal error: %s failed to parse %q with no reportable error", err.Dialect, err.Text)
 }
@@ -0,0 +1,78 @@
+package parser_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func TestParseContextBudget(t *testing.T) {
+	tests := []struct {
+		opts   parser.Options
+		budget string
+	}{
+		{opts: parser.Options{MaxBytes: 4}, budget: "MaxBytes"},
+		{opts: parser.Options{MaxMemoBytes: 1 << 10}, budget: "MaxMemoBytes"},
+		{opts: parser.Options{MaxBytes: 1 << 10, MaxMemoBytes: 1 << 30}},
+	}
+	for _, test := range tests {
+		_, err := parser.ParseContext(context.Background(), "camxes", "mi klama", test.opts)
+		berr, _ := err.(*parser.BudgetError)
+		switch {
+		case test.budget == "" && err != nil:
+			t.Errorf("ParseContext(%+v) failed: %v", test.opts, err)
+		case test.budget != "" && (berr == nil || berr.Budget != test.budget):
+			t.Errorf("ParseContext(%+v) error=%v, want a %s *BudgetError", test.opts, err, test.budget)
+		}
+	}
+}
+
+// A blockingParser is a Parser whose parses fail
+// after they are released.
+type blockingParser struct {
+	started, release chan struct{}
+	errorTree        chan bool
+}
+
+func (p *blockingParser) Parse() (int, bool) {
+	close(p.started)
+	<-p.release
+	return 0, false
+}
+
+func (p *blockingParser) ErrorTree(int) *peg.Fail {
+	p.errorTree <- true
+	return &peg.Fail{}
+}
+
+func (p *blockingParser) ParseTree() *peg.Node { return nil }
+
+func TestParseContextCancel(t *testing.T) {
+	p := &blockingParser{
+		started:   make(chan struct{}),
+		release:   make(chan struct{}),
+		errorTree: make(chan bool, 2),
+	}
+	parser.Register(parser.Dialect{Name: "blocking"}, func(string) parser.Parser { return p })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-p.started
+		cancel()
+	}()
+	if _, err := parser.ParseContext(ctx, "blocking", "mi klama", parser.Options{}); err != context.Canceled {
+		t.Errorf("ParseContext error=%v, want %v", err, context.Canceled)
+	}
+
+	// The abandoned parse stops after its current phase.
+	close(p.release)
+	select {
+	case <-p.errorTree:
+		t.Errorf("the abandoned parse built its error tree")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,198 @@
+package parser
+
+import (
+	"context"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// An ErrorList is a list of errors from a recovering parse, in order of their location.
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	var s string
+	for i, err := range l {
+		if i > 0 {
+			s += "\n"
+		}
+		s += err.Error()
+	}
+	return s
+}
+
+// A Span is a span of text that parsed successfully during a recovering parse.
+type Span struct {
+	// Loc is the location of the beginning of the span in the whole text.
+	Loc
+
+	// Tree is the parse tree of the span.
+	// The tree is the result of parsing only the text of the span.
+	Tree *peg.Node
+}
+
+// ParseRecover parses text like ParseContext,
+// but after a syntax error it resynchronizes at the next sentence boundary,
+// the next I or NIhO word, and continues parsing.
+// It returns the parse trees of the spans of text that parsed, in order.
+// If there were syntax errors, it also returns an ErrorList of all of them,
+// with locations relative to the whole text.
+//
+// If parsing stops for any reason other than a syntax error,
+// such as a done context or an exceeded budget,
+// the spans parsed so far are returned with that error.
+func ParseRecover(ctx context.Context, dialect string, text string, opts Options) ([]Span, error) {
+	if opts.MaxBytes > 0 && len(text) > opts.MaxBytes {
+		return nil, &BudgetError{Budget: "MaxBytes", Limit: opts.MaxBytes, Size: len(text)}
+	}
+	r := recoverer{
+		ctx:     ctx,
+		dialect: dialect,
+		text:    text,
+		index:   NewLineIndex(text),
+		opts:    opts,
+		starts:  sentenceStarts(text),
+	}
+	if err := r.parse(0, len(text)); err != nil {
+		return r.spans, err
+	}
+	if len(r.errs) > 0 {
+		return r.spans, r.errs
+	}
+	return r.spans, nil
+}
+
+type recoverer struct {
+	ctx     context.Context
+	dialect string
+	text    string
+	index   *LineIndex
+	opts    Options
+	starts  []int
+	spans   []Span
+	errs    ErrorList
+}
+
+// parse parses the text between start and end,
+// recording spans and syntax errors,
+// and returns any error that is not a syntax error.
+func (r *recoverer) parse(start, end int) error {
+	for start < end && !whitespace(r.text[start:end]) {
+		tree, err := ParseContext(r.ctx, r.dialect, r.text[start:end], r.opts)
+		if err == nil {
+			r.spans = append(r.spans, Span{Loc: r.index.Loc(start), Tree: tree})
+			return nil
+		}
+		pos, ok := errorByte(err)
+		if !ok {
+			return err
+		}
+		pos += start
+
+		// Parse the sentences before the one containing the error.
+		// An error at a sentence boundary belongs to the sentence before it.
+		sentence := start
+		for _, s := range r.starts {
+			if s > start && s < pos {
+				sentence = s
+			}
+		}
+		if sentence > start {
+			if err := r.parse(start, sentence); err != nil {
+				return err
+			}
+		}
+		r.errs = append(r.errs, relocate(err, r.index, start))
+
+		// Resume at the next sentence.
+		next := end
+		for _, s := range r.starts {
+			if s > start && s >= pos && s < end {
+				next = s
+				break
+			}
+		}
+		start = next
+	}
+	return nil
+}
+
+// errorByte returns the byte offset of a syntax error
+// and whether the error is a syntax error.
+func errorByte(err error) (int, bool) {
+	switch err := err.(type) {
+	case *Error:
+		return err.Byte, true
+	case *ErrorPair:
+		return err.Word.Byte, true
+	}
+	return 0, false
+}
+
+// relocate returns a syntax error for a span of text beginning at byte offset start,
+// with its locations relative to the whole text.
+func relocate(err error, index *LineIndex, start int) error {
+	switch err := err.(type) {
+	case *Error:
+		err.Loc = index.Loc(start + err.Byte)
+	case *ErrorPair:
+		err.Word.Loc = index.Loc(start + err.Word.Byte)
+		err.Raw.Loc = index.Loc(start + err.Raw.Byte)
+	}
+	return err
+}
+
+// sentenceStarts returns the byte offsets of the I and NIhO words in the text.
+//
+// The words are found lexically, without parsing:
+// a word beginning with i or ni'o and followed by a consonant or a pause
+// begins a sentence,
+// except within zo, zoi, la'o, and lo'u … le'u quotations.
+func sentenceStarts(text string) []int {
+	var starts []int
+	var until string // the word ending a quotation
+	var skipNext, delim bool
+	for i := 0; i < len(text); {
+		if strings.IndexByte(SpaceChars, text[i]) >= 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(text) && strings.IndexByte(SpaceChars, text[j]) < 0 {
+			j++
+		}
+		w := strings.Replace(strings.ToLower(text[i:j]), "h", "'", -1)
+		switch {
+		case skipNext:
+			skipNext = false
+		case delim:
+			// Words are split at pauses, but not at commas.
+			// The delimiter is the Lojban word after zoi, without its commas,
+			// and the closing delimiter must be the same word with no commas.
+			until, delim = strings.Trim(w, ","), false
+		case until != "":
+			if w == until {
+				until = ""
+			}
+		case w == "zo":
+			skipNext = true
+		case w == "zoi" || w == "la'o":
+			delim = true
+		case w == "lo'u":
+			until = "le'u"
+		case startsSentence(w):
+			starts = append(starts, i)
+		}
+		i = j
+	}
+	return starts
+}
+
+func startsSentence(w string) bool {
+	for _, p := range [...]string{"i", "ni'o", "no'i"} {
+		if strings.HasPrefix(w, p) && (len(w) == len(p) || strings.IndexByte("bcdfgjklmnprstvxz", w[len(p)]) >= 0) {
+			return true
+		}
+	}
+	return false
+}
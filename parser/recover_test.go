@@ -0,0 +1,111 @@
+package parser_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func TestParseRecover(t *testing.T) {
+	tests := []struct {
+		text string
+		// spans are the line.column and text of each span.
+		spans []string
+		// errs are the line.column of each syntax error.
+		errs []string
+	}{
+		{
+			text:  "mi klama .i do citka",
+			spans: []string{`1.1 "mi klama .i do citka"`},
+		},
+		{
+			text:  "mi klama lo .i do citka",
+			spans: []string{`1.14 "i do citka"`},
+			errs:  []string{"1.14"},
+		},
+		{
+			text:  "mi klama lo\n.i do citka",
+			spans: []string{`2.2 "i do citka"`},
+			errs:  []string{"2.2"},
+		},
+		{
+			text: "ko ku",
+			errs: []string{"1.4"},
+		},
+		{
+			// The sentence before an error parses on its own.
+			text: ".i mi klama lo lo .i do citka ni'o ko ku ku .i mi",
+			spans: []string{
+				`1.20 "i do citka "`,
+				`1.46 "i mi"`,
+			},
+			errs: []string{"1.20", "1.39"},
+		},
+		{
+			// A quoted i does not begin a sentence.
+			text:  "mi cusku zo i lo lo .i do",
+			spans: []string{`1.22 "i do"`},
+			errs:  []string{"1.22"},
+		},
+		{
+			text:  "mi cusku lo'u lo .i le'u lo .i do",
+			spans: []string{`1.30 "i do"`},
+			errs:  []string{"1.30"},
+		},
+		{
+			// A pause after a delimiter is not part of it.
+			text:  "mi cusku zoi gy. lo .i gy. lo lo .i do",
+			spans: []string{`1.35 "i do"`},
+			errs:  []string{"1.35"},
+		},
+		{
+			text:  "mi cusku la'o gy. lo .i gy lo lo .i do",
+			spans: []string{`1.35 "i do"`},
+			errs:  []string{"1.35"},
+		},
+		{
+			// Nor is a comma after the opening delimiter.
+			text:  "mi cusku zoi gy, lo .i gy lo lo .i do",
+			spans: []string{`1.34 "i do"`},
+			errs:  []string{"1.34"},
+		},
+	}
+	for _, test := range tests {
+		spans, err := parser.ParseRecover(context.Background(), "camxes", test.text, parser.Options{})
+		var gotSpans []string
+		for _, s := range spans {
+			gotSpans = append(gotSpans, fmt.Sprintf("%d.%d %q", s.Line, s.Column, s.Tree.Text))
+		}
+		var gotErrs []string
+		if err != nil {
+			errs, ok := err.(parser.ErrorList)
+			if !ok {
+				t.Errorf("ParseRecover(%q) error=%v, want a parser.ErrorList", test.text, err)
+				continue
+			}
+			for _, e := range errs {
+				pe, ok := e.(*parser.Error)
+				if !ok {
+					t.Errorf("ParseRecover(%q) error=%v, want a *parser.Error", test.text, e)
+					continue
+				}
+				gotErrs = append(gotErrs, fmt.Sprintf("%d.%d", pe.Line, pe.Column))
+			}
+		}
+		if !reflect.DeepEqual(gotSpans, test.spans) || !reflect.DeepEqual(gotErrs, test.errs) {
+			t.Errorf("ParseRecover(%q)=%q, %q, want %q, %q", test.text, gotSpans, gotErrs, test.spans, test.errs)
+		}
+	}
+}
+
+func TestParseRecoverBudget(t *testing.T) {
+	opts := parser.Options{MaxBytes: 4}
+	_, err := parser.ParseRecover(context.Background(), "camxes", "mi klama", opts)
+	if _, ok := err.(*parser.BudgetError); !ok {
+		t.Errorf("ParseRecover with MaxBytes=4 error=%v, want a *parser.BudgetError", err)
+	}
+}
@@ -58,11 +58,12 @@ func collapseLists(n *peg.Node) int {
 	return len(n.Kids)
 }
 
+// elidableSuffix is the suffix of the names of elidable terminator rules.
+const elidableSuffix = "_elidible" // elidable is spelled wrong in the PEG grammar files.
+
 // AddElidedTerminators sets the Text of an elided terminator node to the terminator name in all caps.
 // This flags any functions removing empty Nodes to keep the elided terminator Node, as its Text is no longer empty.
 func AddElidedTerminators(n *peg.Node) {
-	const elidableSuffix = "_elidible" // elidable is spelled wrong in the PEG grammar files.
-
 	if n.Text == "" && strings.HasSuffix(n.Name, elidableSuffix) {
 		n.Text = strings.TrimSuffix(n.Name, elidableSuffix)
 		n.Kids = nil
@@ -73,9 +74,16 @@ func AddElidedTerminators(n *peg.Node) {
 	}
 }
 
+// IsElidedTerminator returns whether the node is an elided terminator:
+// an empty terminator node,
+// or one whose text was set to its name by AddElidedTerminators.
+func IsElidedTerminator(n *peg.Node) bool {
+	return terminator(n) != ""
+}
+
 // RemoveMorphology removes all nodes beneath whole words.
 func RemoveMorphology(n *peg.Node) {
-	if isWordNode(n) || n.Name == "zoi_word" {
+	if IsWord(n) {
 		n.Kids = nil
 		return
 	}
@@ -0,0 +1,243 @@
+package parser
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// ElideTerminators removes from a parse tree
+// each explicit terminator that can be elided without changing the parse,
+// such as the ku of {lo mlatu ku cu sipna} but not of {lo mlatu ku sipna}.
+// The tree must be parsed with the dialect, and not simplified by CollapseLists,
+// which removes the names of terminator nodes.
+// A removed terminator is left as an elided terminator node with no text,
+// as though it was never written.
+//
+// Each terminator is removed only if the text of the tree without it,
+// as by Unparse, parses with the dialect to an equivalent tree, as by Format.
+// Terminators followed by free modifiers, as in {lo mlatu ku ui},
+// are never removed, and neither is the separator cu,
+// which is elidable like a terminator but does not end a construct.
+func ElideTerminators(dialect string, n *peg.Node) error {
+	terms := explicitTerminators(nil, n)
+	for {
+		var kept []*peg.Node
+		for _, t := range terms {
+			ok, err := elide(dialect, n, t)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				kept = append(kept, t)
+			}
+		}
+		// Removing a terminator can allow removing an earlier one.
+		if len(kept) == len(terms) {
+			return nil
+		}
+		terms = kept
+	}
+}
+
+// elide removes the terminator t from the tree n
+// and returns whether the tree parses the same without it.
+// If not, the terminator is restored.
+func elide(dialect string, n, t *peg.Node) (bool, error) {
+	text, kids := t.Text, t.Kids
+	t.Text, t.Kids = "", nil
+	tree, err := Parse(dialect, Unparse(n))
+	if err == nil && equivalent(n, tree) {
+		return true, nil
+	}
+	t.Text, t.Kids = text, kids
+	switch err.(type) {
+	case nil, *Error, *ErrorPair, *InternalError:
+		// The terminator is needed.
+		return false, nil
+	}
+	return false, err
+}
+
+// explicitTerminators appends to ts the elidable terminator nodes,
+// other than cu, that are written as a single word.
+func explicitTerminators(ts []*peg.Node, n *peg.Node) []*peg.Node {
+	if strings.HasSuffix(n.Name, elidableSuffix) && terminator(n) == "" {
+		if n.Name == "CU"+elidableSuffix {
+			return ts
+		}
+		if len(grouping(nil, n)) == 1 {
+			ts = append(ts, n)
+		}
+		return ts
+	}
+	for _, k := range n.Kids {
+		ts = explicitTerminators(ts, k)
+	}
+	return ts
+}
+
+// InsertTerminators returns the text with each elided terminator written out,
+// such as {lo mlatu ku cu sipna vau} for {lo mlatu cu sipna}.
+// An elided separator cu is not written out.
+// The rest of the text is kept as it was written.
+// If the text does not parse with the dialect, the parse error is returned.
+//
+// The result is checked to parse to a tree equivalent to the tree of the text
+// with the written terminators made explicit as by AddElidedTerminators, as by Format.
+// If it does not, InsertTerminators returns a *FormatError.
+func InsertTerminators(dialect, text string) (string, error) {
+	tree, err := Parse(dialect, text)
+	if err != nil {
+		return "", err
+	}
+	ranges := Ranges(text, tree)
+	var edits []edit
+	var terms []*peg.Node
+	var visit func(*peg.Node)
+	visit = func(n *peg.Node) {
+		if term := terminator(n); term != "" {
+			if term == "CU" {
+				return
+			}
+			terms = append(terms, n)
+			byte := insertionPoint(text, ranges[n].Start.Byte)
+			if l := len(edits); l > 0 && edits[l-1].start == byte {
+				edits[l-1].text += " " + spell(terminatorWord(term))
+			} else {
+				edits = append(edits, edit{start: byte, end: byte, text: spell(terminatorWord(term))})
+			}
+			return
+		}
+		for _, k := range n.Kids {
+			visit(k)
+		}
+	}
+	visit(tree)
+	for i := range edits {
+		e := &edits[i]
+		if e.start > 0 && !isSpace(text[e.start-1]) {
+			e.text = " " + e.text
+		}
+		if e.end < len(text) && !isPause(text[e.end]) {
+			e.text += " "
+		}
+	}
+	for _, t := range terms {
+		AddElidedTerminators(t)
+	}
+	return check(dialect, tree, applyEdits(text, edits))
+}
+
+// insertionPoint returns the byte offset at which to write
+// an elided terminator at the byte offset of its node.
+// An elided terminator follows the whitespace after the word before it,
+// including the pause before the next word, as in {klama .i},
+// so it is written after the pause ending the word before it, if any,
+// as in {.alis.}, and before the rest of the whitespace.
+func insertionPoint(text string, byte int) int {
+	for byte > 0 && isPause(text[byte-1]) {
+		byte--
+	}
+	end := byte
+	for end < len(text) && isPause(text[end]) && !isSpace(text[end]) {
+		end++
+	}
+	if end < len(text) && isSpace(text[end]) {
+		return end
+	}
+	return byte
+}
+
+// RemoveTerminators returns the text without each terminator
+// that can be elided without changing the parse, as by ElideTerminators,
+// such as {lo mlatu cu sipna} for {lo mlatu ku cu sipna vau}.
+// The rest of the text is kept as it was written.
+// If the text does not parse with the dialect, the parse error is returned.
+//
+// The result is checked to parse to a tree equivalent to the tree of the text
+// after ElideTerminators, as by Format.
+// If it does not, RemoveTerminators returns a *FormatError.
+func RemoveTerminators(dialect, text string) (string, error) {
+	tree, err := Parse(dialect, text)
+	if err != nil {
+		return "", err
+	}
+	ranges := Ranges(text, tree)
+	terms := explicitTerminators(nil, tree)
+	var edits []edit
+	for _, t := range terms {
+		// The text of a terminator node may be followed by whitespace.
+		start := ranges[t].Start.Byte
+		edits = append(edits, edit{start: start, end: start + len(strings.TrimRight(t.Text, SpaceChars))})
+	}
+	if err := ElideTerminators(dialect, tree); err != nil {
+		return "", err
+	}
+	var removed []edit
+	for i, t := range terms {
+		if t.Text != "" {
+			continue
+		}
+		// Remove the space before the terminator,
+		// or the space after it if there is none before it,
+		// so that the words around it stay apart on their lines.
+		e := edits[i]
+		switch {
+		case e.start > 0 && isSpace(text[e.start-1]):
+			for e.start > 0 && isSpace(text[e.start-1]) {
+				e.start--
+			}
+		default:
+			for e.end < len(text) && isSpace(text[e.end]) {
+				e.end++
+			}
+		}
+		removed = append(removed, e)
+	}
+	return check(dialect, tree, applyEdits(text, removed))
+}
+
+// An edit replaces the text from start to end.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// applyEdits returns the text with the edits.
+// An edit that overlaps the edit before it starts at the end of that edit.
+func applyEdits(text string, edits []edit) string {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var b strings.Builder
+	var prev int
+	for _, e := range edits {
+		if e.start < prev {
+			e.start = prev
+		}
+		b.WriteString(text[prev:e.start])
+		b.WriteString(e.text)
+		prev = e.end
+	}
+	b.WriteString(text[prev:])
+	return b.String()
+}
+
+// spell returns the word with a pause before it if it begins with a vowel.
+func spell(word string) string {
+	if strings.ContainsAny(word[:1], "aeiouy") {
+		return "." + word
+	}
+	return word
+}
+
+// isPause returns whether the byte is one of the SpaceChars.
+func isPause(c byte) bool {
+	return strings.IndexByte(SpaceChars, c) >= 0
+}
+
+// isSpace returns whether the byte is a space, tab, or newline,
+// which, unlike the pauses ., ?, and !, separate words visibly.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,104 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func TestInsertTerminators(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "lo mlatu cu sipna", want: "lo mlatu ku cu sipna vau"},
+		{text: "lo mlatu ku cu sipna vau", want: "lo mlatu ku cu sipna vau"},
+		// cu is not a terminator.
+		{text: "lo mlatu ku sipna", want: "lo mlatu ku sipna vau"},
+		{text: "la .alis. klama", want: "la .alis. klama vau"},
+		{text: "lo gerku poi blabi cu klama", want: "lo gerku poi blabi vau ku'o ku cu klama vau"},
+		{text: "mi nelci lo nu do klama", want: "mi nelci lo nu do klama vau kei ku vau"},
+		{text: "mi klama lo zarci\n.i do citka", want: "mi klama lo zarci ku vau\n.i do citka vau"},
+	}
+	for _, test := range tests {
+		got, err := parser.InsertTerminators("camxes", test.text)
+		if err != nil || got != test.want {
+			t.Errorf("InsertTerminators(%q)=%q, %v, want %q", test.text, got, err, test.want)
+			continue
+		}
+		checkSameParse(t, test.text, got)
+	}
+}
+
+func TestRemoveTerminators(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "lo mlatu ku cu sipna vau", want: "lo mlatu cu sipna"},
+		{text: "lo mlatu cu sipna", want: "lo mlatu cu sipna"},
+		{text: "la .alis. cu klama", want: "la .alis. cu klama"},
+		{text: "lo mlatu ku sipna", want: "lo mlatu ku sipna"},
+		{text: "lo mlatu ku ui cu sipna", want: "lo mlatu ku ui cu sipna"},
+		{text: "lo gerku poi blabi ku'o ku cu klama", want: "lo gerku poi blabi cu klama"},
+		{text: "mi nelci lo nu do klama kei ku vau", want: "mi nelci lo nu do klama"},
+		{text: "mi klama lo zarci ku\n.i do citka lo plise ku vau", want: "mi klama lo zarci\n.i do citka lo plise"},
+	}
+	for _, test := range tests {
+		got, err := parser.RemoveTerminators("camxes", test.text)
+		if err != nil || got != test.want {
+			t.Errorf("RemoveTerminators(%q)=%q, %v, want %q", test.text, got, err, test.want)
+			continue
+		}
+		checkSameParse(t, test.text, got)
+	}
+}
+
+// checkSameParse checks that two texts parse to the same tree
+// with their elided terminators made explicit.
+func checkSameParse(t *testing.T, text, got string) {
+	t.Helper()
+	a, err := parser.Parse("camxes", text)
+	if err != nil {
+		t.Errorf("Parse(%q) failed: %v", text, err)
+		return
+	}
+	b, err := parser.Parse("camxes", got)
+	if err != nil {
+		t.Errorf("Parse(%q) failed: %v", got, err)
+		return
+	}
+	if sa, sb := shape(a), shape(b); sa != sb {
+		t.Errorf("Parse(%q)=%s,\nParse(%q)=%s", text, sa, got, sb)
+	}
+}
+
+// shape returns the rule names and words of a tree
+// with its elided terminators made explicit,
+// ignoring whitespace, morphology, and how the words are written.
+func shape(n *peg.Node) string {
+	parser.AddElidedTerminators(n)
+	var b strings.Builder
+	var visit func(*peg.Node)
+	visit = func(n *peg.Node) {
+		name := parser.Rule(n.Name)
+		switch {
+		case parser.IsWordRule(name) || parser.IsWord(n):
+			words := strings.Join(strings.FieldsFunc(n.Text, func(r rune) bool {
+				return strings.ContainsRune(parser.SpaceChars, r)
+			}), " ")
+			b.WriteString(" " + name + ":" + strings.Replace(strings.ToLower(words), "h", "'", -1))
+		case len(n.Kids) > 0:
+			b.WriteString(" " + name + "(")
+			for _, k := range n.Kids {
+				visit(k)
+			}
+			b.WriteString(")")
+		}
+	}
+	visit(n)
+	return b.String()
+}
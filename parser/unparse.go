@@ -0,0 +1,318 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// Unparse returns the text of the words of a parse tree with normalized spacing.
+// The tree may be raw or simplified by RemoveMorphology, RemoveSpace, or CollapseLists.
+//
+// Words are separated by a single space.
+// A pause, written ., is added before a word beginning with a vowel
+// and around a cmevla, as in {.i mi du la .djan.},
+// and after the opening and before the closing delimiter
+// of a zoi or la'o quotation, as in {zoi gy. hello world .gy}.
+// The text of the quotation is written as it was in the parsed text,
+// including pause characters such as ? and !, which the grammar treats as whitespace,
+// even if whitespace was removed from the tree by RemoveSpace.
+// Elided terminators made explicit by AddElidedTerminators
+// are written as their terminator cmavo, such as ku'o for KUhO.
+func Unparse(n *peg.Node) string {
+	return UnparseOptions{}.Unparse(n)
+}
+
+// UnparseOptions are options for unparsing a parse tree.
+type UnparseOptions struct {
+	// Lines is whether to begin each sentence on a new line,
+	// at each I word, and each paragraph after a blank line,
+	// at each NIhO word.
+	Lines bool
+
+	// Normalize is whether to write words in lowercase, with ' for h,
+	// except for the text of zoi quotations.
+	Normalize bool
+}
+
+// Unparse returns the text of a parse tree like the Unparse function,
+// but with the options.
+func (opts UnparseOptions) Unparse(n *peg.Node) string {
+	u := unparser{opts: opts}
+	u.unparse(n)
+	return u.b.String()
+}
+
+type unparser struct {
+	opts UnparseOptions
+	b    strings.Builder
+
+	// last is the last word written, or nil.
+	last *peg.Node
+
+	// closing is whether the next word is the closing delimiter of a quotation.
+	closing bool
+}
+
+func (u *unparser) unparse(n *peg.Node) {
+	switch {
+	case terminator(n) != "":
+		if n.Text != "" {
+			u.word(n, terminatorWord(terminator(n)))
+		}
+	case len(n.Kids) > 0 && !IsWord(n):
+		if open, close, ok := quotation(n); ok {
+			u.quotation(n, open, close)
+			return
+		}
+		// The text of a node is empty if it has only elided terminators,
+		// so its kids are visited even if its text is whitespace.
+		for _, k := range n.Kids {
+			u.unparse(k)
+		}
+	case whitespace(n.Text):
+	default:
+		u.word(n, strings.Trim(n.Text, SpaceChars))
+	}
+}
+
+// quotation writes a zoi or la'o quotation node
+// whose kids at open and close are its delimiters.
+func (u *unparser) quotation(n *peg.Node, open, close int) {
+	for _, k := range n.Kids[:open+1] {
+		u.unparse(k)
+	}
+	if !strings.HasSuffix(u.b.String(), ".") {
+		u.b.WriteString(".")
+	}
+	if text := quoteText(n, open, close); text != "" {
+		u.b.WriteString(" ")
+		u.b.WriteString(text)
+	}
+	u.closing = true
+	for _, k := range n.Kids[close:] {
+		u.unparse(k)
+	}
+}
+
+func (u *unparser) word(n *peg.Node, text string) {
+	if u.opts.Normalize {
+		text = normalize(n, text)
+	}
+	switch {
+	case u.last == nil:
+	case u.opts.Lines && n.Name == "NIhO" && u.last.Name != "NIhO":
+		u.b.WriteString("\n\n")
+	case u.opts.Lines && n.Name == "I" && u.last.Name != "NIhO":
+		u.b.WriteString("\n")
+	default:
+		u.b.WriteString(" ")
+	}
+	if u.closing || n.Name == "CMEVLA" || strings.ContainsAny(text[:1], "aeiouyAEIOUY") {
+		u.b.WriteString(".")
+	}
+	u.b.WriteString(text)
+	if n.Name == "CMEVLA" {
+		u.b.WriteString(".")
+	}
+	u.last = n
+	u.closing = false
+}
+
+// quotation returns the indices of the kids of a zoi or la'o quotation node
+// that are its opening and closing delimiters:
+// the first word after its ZOI word, and its last word.
+func quotation(n *peg.Node) (open, close int, ok bool) {
+	open, close = -1, -1
+	zoi := false
+	for i, k := range n.Kids {
+		switch {
+		case k.Name == "ZOI" || k.Name == "MUhOI":
+			zoi = true
+		case !zoi || whitespace(k.Text):
+		case open < 0:
+			open = i
+		default:
+			close = i
+		}
+	}
+	return open, close, close > open && open >= 0
+}
+
+// quoteText returns the text of a quotation node between its delimiters,
+// the kids at open and close, as it was parsed:
+// the words of the quotation and the whitespace and pauses between them,
+// including pause characters such as ? and !, which the grammar treats as whitespace.
+// The pauses directly after the opening and before the closing delimiter
+// and the whitespace around the text are not included.
+//
+// The text is taken from the text of the node,
+// so it is the same after RemoveSpace removes the whitespace within it.
+func quoteText(n *peg.Node, open, close int) string {
+	var pos, start, end int
+	for i, k := range n.Kids[:close+1] {
+		// Only whitespace is removed from between the kids,
+		// so each is found at the first occurrence of its text.
+		at := strings.Index(n.Text[pos:], k.Text)
+		if at < 0 {
+			return ""
+		}
+		pos += at
+		switch i {
+		case open:
+			start = pos + len(k.Text)
+		case close:
+			end = pos
+		}
+		pos += len(k.Text)
+	}
+	text := strings.TrimSuffix(strings.TrimPrefix(n.Text[start:end], "."), ".")
+	return strings.Trim(text, " \t\n\r")
+}
+
+// normalize returns the text of a word in lowercase with ' for h,
+// or the text unchanged for the text of a zoi quotation.
+func normalize(n *peg.Node, text string) string {
+	if n.Name == "zoi_word" {
+		return text
+	}
+	return strings.Replace(strings.ToLower(text), "h", "'", -1)
+}
+
+// terminator returns the name of the elided terminator if the node is one,
+// either empty or made explicit by AddElidedTerminators,
+// and otherwise the empty string.
+func terminator(n *peg.Node) string {
+	term := strings.TrimSuffix(n.Name, elidableSuffix)
+	if term == n.Name || n.Text != "" && (n.Text != term || len(n.Kids) > 0) {
+		return ""
+	}
+	return term
+}
+
+// terminatorWord returns the cmavo of a terminator selma'o,
+// which, for terminators, is the cmavo that names it: KUhO is ku'o.
+func terminatorWord(term string) string {
+	return strings.ToLower(strings.Replace(term, "h", "'", -1))
+}
+
+// A FormatError is returned by Format
+// if the unparsed text of a tree does not parse to an equivalent tree.
+// It indicates a bug in this package.
+type FormatError struct {
+	// Dialect is the name of the dialect.
+	Dialect string
+
+	// Text is the unparsed text.
+	Text string
+
+	// Err is the error parsing Text,
+	// or nil if Text parsed to a different tree.
+	Err error
+}
+
+func (err *FormatError) Error() string {
+	if err.Err != nil {
+		return fmt.Sprintf("format error: %s failed to parse %q: %s", err.Dialect, err.Text, err.Err)
+	}
+	return fmt.Sprintf("format error: %s parsed %q to a different tree", err.Dialect, err.Text)
+}
+
+// Format returns the text of a parse tree like Unparse,
+// and checks that the text parses with the dialect to an equivalent tree:
+// one with the same words, including explicit elided terminators,
+// and the same quoted text of zoi and la'o quotations,
+// grouped the same way, ignoring rule names, whitespace, morphology,
+// and the case of words and whether they are written with h or '.
+// If it does not, Format returns a *FormatError.
+func Format(dialect string, n *peg.Node) (string, error) {
+	return UnparseOptions{}.Format(dialect, n)
+}
+
+// Format returns the text of a parse tree like the Format function,
+// but with the options.
+func (opts UnparseOptions) Format(dialect string, n *peg.Node) (string, error) {
+	return check(dialect, n, opts.Unparse(n))
+}
+
+// check returns the text if it parses with the dialect
+// to a tree equivalent to the tree n,
+// and otherwise a *FormatError.
+func check(dialect string, n *peg.Node, text string) (string, error) {
+	tree, err := Parse(dialect, text)
+	if err != nil {
+		return "", &FormatError{Dialect: dialect, Text: text, Err: err}
+	}
+	if !equivalent(n, tree) {
+		return "", &FormatError{Dialect: dialect, Text: text}
+	}
+	return text, nil
+}
+
+// equivalent returns whether two trees have the same words grouped the same way.
+func equivalent(a, b *peg.Node) bool {
+	as, bs := grouping(nil, a), grouping(nil, b)
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// grouping appends the words of the tree to ws,
+// with each node that has more than one kid with words
+// bracketed by ( and ), which are not words,
+// and the text of each quotation, as by quoteText, quoted as by strconv.Quote.
+// This is the same for a tree before and after CollapseLists,
+// or before and after AddElidedTerminators if the terminators were explicit.
+func grouping(ws []string, n *peg.Node) []string {
+	var kids [][]string
+	switch {
+	case terminator(n) != "":
+		if n.Text != "" {
+			ws = append(ws, terminatorWord(terminator(n)))
+		}
+		return ws
+	case len(n.Kids) > 0 && !IsWord(n):
+		open, close, ok := quotation(n)
+		if !ok {
+			kids = groupings(n.Kids)
+			break
+		}
+		kids = groupings(n.Kids[:open+1])
+		kids = append(kids, []string{strconv.Quote(quoteText(n, open, close))})
+		kids = append(kids, groupings(n.Kids[close:])...)
+	case whitespace(n.Text):
+		return ws
+	default:
+		return append(ws, normalize(n, strings.Trim(n.Text, SpaceChars)))
+	}
+	switch len(kids) {
+	case 0:
+		return ws
+	case 1:
+		return append(ws, kids[0]...)
+	}
+	ws = append(ws, "(")
+	for _, kws := range kids {
+		ws = append(ws, kws...)
+	}
+	return append(ws, ")")
+}
+
+// groupings returns the groupings of the nodes that have words.
+func groupings(ns []*peg.Node) [][]string {
+	var gs [][]string
+	for _, n := range ns {
+		if g := grouping(nil, n); len(g) > 0 {
+			gs = append(gs, g)
+		}
+	}
+	return gs
+}
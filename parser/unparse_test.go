@@ -0,0 +1,94 @@
+package parser_test
+
+import (
+	"testing"
+
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+)
+
+func TestFormatQuotes(t *testing.T) {
+	tests := []struct {
+		text string
+		// want is the formatted text of the raw and simplified trees.
+		want string
+	}{
+		{
+			text: "zoi gy. hello world gy. cusku",
+			want: "zoi gy. hello world .gy cusku",
+		},
+		{
+			text: "zoi gy. hello   world gy. cusku",
+			want: "zoi gy. hello   world .gy cusku",
+		},
+		{
+			text: "ZOI GY. Hello, World! GY. cusku",
+			want: "zoi gy. Hello, World! .gy cusku",
+		},
+		{
+			text: "zoi gy.gy. cusku",
+			want: "zoi gy. .gy cusku",
+		},
+		{
+			text: "la'o dy. Mark  Twain dy. cusku",
+			want: "la'o dy. Mark  Twain .dy cusku",
+		},
+		{
+			text: "mi cusku zoi gy. hi .gy. .e la'o dy. Djan .dy.",
+			want: "mi cusku zoi gy. hi .gy .e la'o dy. Djan .dy",
+		},
+		// The grammar parses ., ?, and ! as whitespace, even in a quotation.
+		{
+			text: "mi cusku zoi gy ?! gy",
+			want: "mi cusku zoi gy. ?! .gy",
+		},
+		{
+			text: "mi cusku zoi gy .i gy",
+			want: "mi cusku zoi gy. .i .gy",
+		},
+		{
+			text: "la'o gy ! gy",
+			want: "la'o gy. ! .gy",
+		},
+		{
+			text: "zoi gy hello. gy .i mi cusku",
+			want: "zoi gy. hello. .gy .i mi cusku",
+		},
+		{
+			text: "zoi gy . gy",
+			want: "zoi gy. . .gy",
+		},
+	}
+	opts := parser.UnparseOptions{Normalize: true}
+	for _, dialect := range []string{"camxes", "ilmentufa", "maftufa"} {
+		for _, test := range tests {
+			tree, err := parser.Parse(dialect, test.text)
+			if err != nil {
+				t.Errorf("%s: Parse(%q) failed: %v", dialect, test.text, err)
+				continue
+			}
+			got, err := opts.Format(dialect, tree)
+			if err != nil || got != test.want {
+				t.Errorf("%s: Format(%q)=%q, %v, want %q", dialect, test.text, got, err, test.want)
+			}
+
+			parser.RemoveMorphology(tree)
+			parser.RemoveSpace(tree)
+			parser.CollapseLists(tree)
+			got, err = opts.Format(dialect, tree)
+			if err != nil || got != test.want {
+				t.Errorf("%s: simplified Format(%q)=%q, %v, want %q", dialect, test.text, got, err, test.want)
+			}
+
+			// The formatted text formats to itself.
+			tree, err = parser.Parse(dialect, test.want)
+			if err != nil {
+				t.Errorf("%s: Parse(%q) failed: %v", dialect, test.want, err)
+				continue
+			}
+			if got, err := opts.Format(dialect, tree); err != nil || got != test.want {
+				t.Errorf("%s: Format(%q)=%q, %v, want %q", dialect, test.want, got, err, test.want)
+			}
+		}
+	}
+}
@@ -23,14 +23,50 @@ func walk(n *peg.Fail, f func(*peg.Fail) bool) bool {
 func isWordNode(n interface{}) bool {
 	switch n := n.(type) {
 	case *peg.Fail:
-		return n.Name != "h" && isCaps(n.Name)
+		return IsWordRule(n.Name)
 	case *peg.Node:
-		return n.Name != "h" && isCaps(n.Name)
+		return IsWordRule(n.Name)
 	default:
 		panic("bad node type")
 	}
 }
 
+// IsWord returns whether the node is a whole word,
+// such as a selma'o, BRIVLA, or CMEVLA node or the word of a zoi quotation.
+// The kids of a word node are its morphology.
+func IsWord(n *peg.Node) bool {
+	return isWordNode(n) || n.Name == "zoi_word"
+}
+
+// IsWordRule returns whether the rule name is that of a whole word,
+// a selma'o or word class such as BRIVLA or CMEVLA:
+// all caps with no _, but not solely the letter "h".
+func IsWordRule(name string) bool {
+	return name != "h" && isCaps(name)
+}
+
+// Rule returns the name of the grammar construct of a rule name.
+// Numbered sub-rules are named by their parent,
+// for example sumti_6 is sumti and bridi_tail_t1 is bridi_tail,
+// and word clauses and elidable terminators by their selma'o,
+// for example KOhA_clause is KOhA and KU_elidible is KU.
+func Rule(name string) string {
+	for _, suffix := range [...]string{"_clause", elidableSuffix} {
+		if s := strings.TrimSuffix(name, suffix); s != name && IsWordRule(s) {
+			return s
+		}
+	}
+	i := strings.LastIndexByte(name, '_')
+	if i < 0 {
+		return name
+	}
+	n := strings.TrimPrefix(name[i+1:], "t")
+	if n == "" || strings.Trim(n, "0123456789") != "" {
+		return name
+	}
+	return name[:i]
+}
+
 const caps = "hABCDEFGIJKLMNOPRSTUVXYZ"
 
 func isCaps(s string) bool {
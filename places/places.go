@@ -0,0 +1,422 @@
+// Package places assigns the terms of each bridi
+// to the places of its selbri, x1 to x5,
+// following the rules of The Complete Lojban Language, chapter 9:
+// terms fill the places in order, beginning with x1 before the selbri,
+// or x2 after the selbri if no term precedes it;
+// an FA tag, such as fe, fills its place and continues the order from it;
+// SE conversions, such as se, exchange x1 with another place;
+// tagged terms, such as pi'o lo karce or fi'o … fe'u, fill no place;
+// and be … bei … links fill the places of the tanru unit they follow,
+// beginning with x2.
+package places
+
+import (
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/ast"
+	"within.website/johaus/parser/morph"
+)
+
+// A Bridi is a selbri and the terms that fill its places.
+type Bridi struct {
+	// Sentence is the sentence of the bridi,
+	// or nil for a description, such as lo klama be le zarci,
+	// whose linked terms fill places of its selbri.
+	Sentence *ast.Sentence
+
+	// Description is the description of a bridi with no Sentence,
+	// or nil.
+	Description *ast.Sumti
+
+	// Selbri is the selbri.
+	// A sentence with connected bridi tails, such as {mi klama gi'e citka},
+	// has a Bridi for each of them, each with the terms before the selbri.
+	Selbri *ast.Selbri
+
+	// Head is the tanru unit of the brivla or other word
+	// whose places the terms of the bridi fill,
+	// such as klama for {sutra se klama},
+	// or nil if the selbri is a logically connected tanru.
+	Head *ast.TanruUnit
+
+	// Conversions are the SE and JAI words converting the Head,
+	// outermost first, such as se for se klama.
+	Conversions []string
+
+	// Places are the terms of the bridi and the places they fill, in order.
+	Places []Place
+}
+
+// A Place is a term and the place that it fills.
+type Place struct {
+	// Term is the term.
+	Term *ast.Term
+
+	// Unit is the tanru unit whose place the term fills:
+	// the Head of the Bridi, or the unit of a be … bei link.
+	// It is nil for a term that fills no place,
+	// and for the places of a logically connected selbri.
+	Unit *ast.TanruUnit
+
+	// Place is the place filled, 1 for x1,
+	// of the selbri or linked unit as it is written, with its conversions,
+	// or 0 for a term that fills no place:
+	// a tagged term, a fi'a term, or a na ku term.
+	Place int
+
+	// Underlying is the place of the Unit's word without its conversions,
+	// such as 2 for the x1 of se klama,
+	// or 0 if it is not known, as with a JAI conversion.
+	Underlying int
+}
+
+// Assign returns the place assignments of the bridi in a parse tree,
+// converted by ast.Convert, which supports the camxes, camxes-beta,
+// and ilmentufa dialects.
+// If the tree contains a construct that ast.Convert does not support,
+// Assign returns the places of the bridi converted before it
+// and the *ast.UnsupportedError.
+func Assign(tree *peg.Node) ([]Bridi, error) {
+	t, err := ast.Convert(tree)
+	return AssignText(t), err
+}
+
+// AssignText returns the place assignments of the bridi in the text,
+// including those of bridi within other bridi,
+// such as in relative clauses and abstractions,
+// each following the bridi containing it.
+func AssignText(t *ast.Text) []Bridi {
+	var a assigner
+	a.text(t)
+	return a.bridi
+}
+
+type assigner struct {
+	bridi []Bridi
+}
+
+func (a *assigner) text(t *ast.Text) {
+	if t == nil {
+		return
+	}
+	for _, p := range t.Paragraphs {
+		for _, s := range p.Sentences {
+			a.sentence(s)
+		}
+		a.frees(p.Free)
+	}
+	a.frees(t.Free)
+}
+
+func (a *assigner) sentence(s *ast.Sentence) {
+	if s == nil {
+		return
+	}
+	if s.Tail != nil {
+		a.tail(s, s.Terms, s.Tail, nil)
+	}
+	a.terms(s.Prenex)
+	a.terms(s.Terms)
+	a.inTail(s.Tail)
+	a.text(s.Group)
+	a.frees(s.Free)
+}
+
+// tail adds the bridi of a bridi tail of the sentence,
+// with the terms before and after it.
+func (a *assigner) tail(s *ast.Sentence, before []*ast.Term, t *ast.BridiTail, after []*ast.Term) {
+	after = append(t.Terms[:len(t.Terms):len(t.Terms)], after...)
+	switch {
+	case t.Selbri != nil:
+		before = append(before[:len(before):len(before)], t.Head...)
+		a.bridi = append(a.bridi, assign(s, t.Selbri, before, after))
+	case len(t.Tails) > 0:
+		for _, u := range t.Tails {
+			a.tail(s, before, u, after)
+		}
+	default:
+		for _, u := range t.Sentences {
+			if u.Tail != nil {
+				a.tail(u, append(before[:len(before):len(before)], u.Terms...), u.Tail, after)
+			}
+		}
+	}
+}
+
+// inTail adds the bridi within the terms and selbri of a bridi tail.
+func (a *assigner) inTail(t *ast.BridiTail) {
+	if t == nil {
+		return
+	}
+	a.terms(t.Head)
+	a.selbri(t.Selbri)
+	for _, u := range t.Tails {
+		a.inTail(u)
+	}
+	for _, u := range t.Sentences {
+		if u.Tail == nil {
+			a.sentence(u)
+			continue
+		}
+		a.terms(u.Prenex)
+		a.terms(u.Terms)
+		a.inTail(u.Tail)
+		a.frees(u.Free)
+	}
+	a.terms(t.Terms)
+}
+
+// assign returns the Bridi of a selbri with the terms before and after it.
+func assign(s *ast.Sentence, sel *ast.Selbri, before, after []*ast.Term) Bridi {
+	b := Bridi{Sentence: s, Selbri: sel}
+	b.Head, b.Conversions = head(sel.Tanru)
+	next := 1
+	b.Places = fill(b.Places, b.Head, b.Conversions, before, &next)
+	if next == 1 {
+		// With no terms before the selbri, the x1 is unspecified.
+		next = 2
+	}
+	b.Places = fill(b.Places, b.Head, b.Conversions, after, &next)
+	b.Places = links(b.Places, sel.Tanru)
+	return b
+}
+
+// fill appends the places of the terms of a unit with the conversions,
+// filling places in order beginning with *next,
+// and sets *next to the place after the last filled.
+func fill(ps []Place, u *ast.TanruUnit, convs []string, terms []*ast.Term, next *int) []Place {
+	for _, t := range terms {
+		switch {
+		case t.Sumti == nil && t.Tag == nil && t.FA == "" && t.NA == "":
+			// A termset or connected terms,
+			// whose operands each fill the same places.
+			start, end := *next, -1
+			for _, op := range operands(t) {
+				n := start
+				ps = fill(ps, u, convs, op, &n)
+				if end < 0 {
+					end = n
+				}
+			}
+			if end >= 0 {
+				*next = end
+			}
+		case t.Tag != nil || t.NA != "":
+			ps = append(ps, Place{Term: t})
+		case t.FA != "":
+			n := faPlace(t.FA)
+			if n == 0 {
+				ps = append(ps, Place{Term: t})
+				continue
+			}
+			ps = append(ps, place(t, u, convs, n))
+			*next = n + 1
+		default:
+			ps = append(ps, place(t, u, convs, *next))
+			*next++
+		}
+	}
+	return ps
+}
+
+// operands returns the terms of each operand of a termset or connected term.
+func operands(t *ast.Term) [][]*ast.Term {
+	if t.Connective == nil {
+		return [][]*ast.Term{t.Terms}
+	}
+	var ops [][]*ast.Term
+	for _, u := range t.Terms {
+		if u.Sumti == nil && u.Tag == nil && u.FA == "" && u.NA == "" && u.Connective == nil {
+			ops = append(ops, u.Terms)
+		} else {
+			ops = append(ops, []*ast.Term{u})
+		}
+	}
+	return ops
+}
+
+func place(t *ast.Term, u *ast.TanruUnit, convs []string, n int) Place {
+	p := Place{Term: t, Unit: u, Place: n, Underlying: underlying(n, convs)}
+	if u == nil {
+		p.Underlying = 0
+	}
+	return p
+}
+
+// links appends the places of the be … bei links of the units of the tanru.
+func links(ps []Place, t *ast.Tanru) []Place {
+	if t == nil {
+		return ps
+	}
+	for _, op := range t.Tanru {
+		ps = links(ps, op)
+	}
+	for _, u := range t.Units {
+		ps = links(ps, u.Group)
+		if len(u.Links) == 0 {
+			continue
+		}
+		h, convs := unitHead(u)
+		next := 2
+		ps = fill(ps, h, convs, u.Links, &next)
+	}
+	return ps
+}
+
+// head returns the unit of the word at the head of the tanru,
+// and the conversions applied to it, outermost first,
+// or nil if the tanru is logically connected.
+func head(t *ast.Tanru) (*ast.TanruUnit, []string) {
+	if t == nil || t.Connective != nil || len(t.Units) == 0 {
+		return nil, nil
+	}
+	return unitHead(t.Units[len(t.Units)-1])
+}
+
+// unitHead returns the unit of the word of a tanru unit,
+// which is the unit itself unless it is a group, such as ke … ke'e,
+// and the conversions applied to it, outermost first.
+func unitHead(u *ast.TanruUnit) (*ast.TanruUnit, []string) {
+	convs := u.Conversions
+	if u.Group == nil {
+		return u, convs
+	}
+	h, inner := head(u.Group)
+	if h == nil {
+		return nil, nil
+	}
+	return h, append(convs[:len(convs):len(convs)], inner...)
+}
+
+// underlying returns the place of a word without its conversions, outermost first,
+// filled by place n of the converted word, or 0 if it is not known.
+func underlying(n int, convs []string) int {
+	for _, c := range convs {
+		m := sePlace(c)
+		switch {
+		case m == 0:
+			return 0
+		case n == 1:
+			n = m
+		case n == m:
+			n = 1
+		}
+	}
+	return n
+}
+
+// sePlace returns the place exchanged with x1 by a SE word,
+// such as 2 for se, or 0 if the word is not a SE word.
+func sePlace(se string) int {
+	switch morph.Normalize(se) {
+	case "se":
+		return 2
+	case "te":
+		return 3
+	case "ve":
+		return 4
+	case "xe":
+		return 5
+	}
+	return 0
+}
+
+// faPlace returns the place of a FA word, such as 2 for fe,
+// or 0 for fi'a, which asks which place is filled.
+func faPlace(fa string) int {
+	switch morph.Normalize(fa) {
+	case "fa":
+		return 1
+	case "fe":
+		return 2
+	case "fi":
+		return 3
+	case "fo":
+		return 4
+	case "fu":
+		return 5
+	}
+	return 0
+}
+
+// terms adds the bridi within the terms.
+func (a *assigner) terms(ts []*ast.Term) {
+	for _, t := range ts {
+		if t.Tag != nil {
+			a.selbri(t.Tag.Selbri)
+		}
+		a.sumti(t.Sumti)
+		a.terms(t.Terms)
+	}
+}
+
+// sumti adds the bridi within the sumti,
+// and the bridi of a description whose selbri has linked terms.
+func (a *assigner) sumti(s *ast.Sumti) {
+	if s == nil {
+		return
+	}
+	a.sumti(s.Possessor)
+	if s.Selbri != nil {
+		if ps := links(nil, s.Selbri.Tanru); len(ps) > 0 {
+			h, convs := head(s.Selbri.Tanru)
+			a.bridi = append(a.bridi, Bridi{
+				Description: s,
+				Selbri:      s.Selbri,
+				Head:        h,
+				Conversions: convs,
+				Places:      ps,
+			})
+		}
+		a.selbri(s.Selbri)
+	}
+	a.sumti(s.Inner)
+	if s.Quote != nil {
+		a.text(s.Quote.Text)
+	}
+	for _, rc := range s.RelativeClauses {
+		a.sentence(rc.Sentence)
+		if rc.Term != nil {
+			a.terms([]*ast.Term{rc.Term})
+		}
+	}
+	for _, t := range s.Sumti {
+		a.sumti(t)
+	}
+	a.frees(s.Free)
+}
+
+// selbri adds the bridi within the selbri,
+// such as in its links and abstractions.
+func (a *assigner) selbri(s *ast.Selbri) {
+	if s == nil {
+		return
+	}
+	if s.Tag != nil {
+		a.selbri(s.Tag.Selbri)
+	}
+	a.tanru(s.Tanru)
+}
+
+func (a *assigner) tanru(t *ast.Tanru) {
+	if t == nil {
+		return
+	}
+	for _, op := range t.Tanru {
+		a.tanru(op)
+	}
+	for _, u := range t.Units {
+		a.tanru(u.Group)
+		a.sentence(u.Abstraction)
+		a.sumti(u.Sumti)
+		a.terms(u.Links)
+	}
+}
+
+func (a *assigner) frees(fs []*ast.Free) {
+	for _, f := range fs {
+		a.terms(f.Terms)
+		a.sumti(f.Sumti)
+		a.selbri(f.Selbri)
+		a.text(f.Text)
+	}
+}
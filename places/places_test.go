@@ -0,0 +1,145 @@
+package places_test
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+	"within.website/johaus/places"
+)
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		text string
+		// bridi are the places of each bridi, in order:
+		// each term, the place it fills, and the underlying place of its word,
+		// or - for a term that fills no place.
+		bridi [][]string
+	}{
+		{
+			text:  "mi klama lo zarci",
+			bridi: [][]string{{"mi x1 klama1", "lo zarci x2 klama2"}},
+		},
+		{
+			// With no term before the selbri, the first term after it is x2.
+			text:  "klama lo zarci",
+			bridi: [][]string{{"lo zarci x2 klama2"}},
+		},
+		{
+			text:  "klama fa mi",
+			bridi: [][]string{{"fa mi x1 klama1"}},
+		},
+		{
+			// FA continues the order from its place.
+			text:  "mi klama fi lo zdani lo karce fe lo zarci",
+			bridi: [][]string{{"mi x1 klama1", "fi lo zdani x3 klama3", "lo karce x4 klama4", "fe lo zarci x2 klama2"}},
+		},
+		{
+			text:  "fe mi klama do",
+			bridi: [][]string{{"fe mi x2 klama2", "do x3 klama3"}},
+		},
+		{
+			text:  "mi se klama lo zarci",
+			bridi: [][]string{{"mi x1 klama2", "lo zarci x2 klama1"}},
+		},
+		{
+			text:  "lo zarci cu se te klama mi",
+			bridi: [][]string{{"lo zarci x1 klama2", "mi x2 klama3"}},
+		},
+		{
+			text:  "mi te se klama do",
+			bridi: [][]string{{"mi x1 klama3", "do x2 klama1"}},
+		},
+		{
+			// The conversion of the head of a tanru.
+			text:  "mi sutra se klama lo zarci",
+			bridi: [][]string{{"mi x1 klama2", "lo zarci x2 klama1"}},
+		},
+		{
+			text:  "mi klama be lo zarci bei lo zdani",
+			bridi: [][]string{{"mi x1 klama1", "lo zarci x2 klama2", "lo zdani x3 klama3"}},
+		},
+		{
+			text:  "mi se klama be lo zarci",
+			bridi: [][]string{{"mi x1 klama2", "lo zarci x2 klama1"}},
+		},
+		{
+			// The linked terms of a description fill places of its selbri.
+			text: "lo klama be lo zarci cu sutra",
+			bridi: [][]string{
+				{"lo klama be lo zarci x1 sutra1"},
+				{"lo zarci x2 klama2"},
+			},
+		},
+		{
+			// Tagged, fi'a, and na ku terms fill no place.
+			text:  "mi klama pi'o lo karce lo zarci",
+			bridi: [][]string{{"mi x1 klama1", "pi'o lo karce -", "lo zarci x2 klama2"}},
+		},
+		{
+			text:  "mi klama fi'a lo zarci",
+			bridi: [][]string{{"mi x1 klama1", "fi'a lo zarci -"}},
+		},
+		{
+			text:  "mi na ku klama",
+			bridi: [][]string{{"mi x1 klama1", "na ku -"}},
+		},
+		{
+			text: "mi klama gi'e citka lo plise",
+			bridi: [][]string{
+				{"mi x1 klama1"},
+				{"mi x1 citka1", "lo plise x2 citka2"},
+			},
+		},
+		{
+			text: "mi nelci lo nu do klama",
+			bridi: [][]string{
+				{"mi x1 nelci1", "lo nu do klama x2 nelci2"},
+				{"do x1 klama1"},
+			},
+		},
+	}
+	for _, test := range tests {
+		tree, err := parser.Parse("camxes", test.text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.text, err)
+			continue
+		}
+		bs, err := places.Assign(tree)
+		if err != nil {
+			t.Errorf("Assign(%q) failed: %v", test.text, err)
+			continue
+		}
+		var got [][]string
+		for _, b := range bs {
+			var ps []string
+			for _, p := range b.Places {
+				ps = append(ps, placeString(p))
+			}
+			got = append(got, ps)
+		}
+		if !reflect.DeepEqual(got, test.bridi) {
+			t.Errorf("Assign(%q)=%q, want %q", test.text, got, test.bridi)
+		}
+	}
+}
+
+func placeString(p places.Place) string {
+	s := unparse(p.Term.Tree)
+	if p.Place == 0 {
+		return s + " -"
+	}
+	s += " x" + strconv.Itoa(p.Place)
+	if p.Unit != nil {
+		s += " " + strings.ToLower(p.Unit.Word) + strconv.Itoa(p.Underlying)
+	}
+	return s
+}
+
+func unparse(n *peg.Node) string {
+	return strings.TrimSpace(parser.UnparseOptions{Normalize: true}.Unparse(n))
+}
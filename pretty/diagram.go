@@ -0,0 +1,194 @@
+package pretty
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+)
+
+// A diagramNode is a node of a tree diagram.
+// Diagrams omit anonymous nodes, attaching their kids to their parent,
+// and nodes with no text.
+// The text of a leaf is drawn as a word beneath the leaf's rule name.
+type diagramNode struct {
+	label string
+	word  bool
+
+	// empty is whether the node is the empty word of an elided terminator.
+	empty bool
+
+	kids []*diagramNode
+
+	// x is the center of the node, y is its depth,
+	// and width is the width of its subtree,
+	// set by measure and place.
+	x     float64
+	y     int
+	width float64
+}
+
+type diagramOptions struct {
+	// hideMorphology is whether to draw whole words as leaves,
+	// omitting the nodes beneath them.
+	hideMorphology bool
+
+	// showElided is whether to draw elided terminators
+	// as their rule name above an empty word.
+	showElided bool
+}
+
+func diagram(n *peg.Node, opts diagramOptions) *diagramNode {
+	if n.Name == "" && len(n.Kids) == 0 {
+		return &diagramNode{label: n.Text, word: true}
+	}
+	d := &diagramNode{label: n.Name}
+	switch {
+	case opts.showElided && parser.IsElidedTerminator(n):
+		d.kids = []*diagramNode{{word: true, empty: true}}
+	case len(n.Kids) == 0 || opts.hideMorphology && parser.IsWord(n):
+		d.kids = []*diagramNode{{label: n.Text, word: true}}
+	default:
+		d.kids = diagramKids(n.Kids, opts)
+	}
+	return d
+}
+
+func diagramKids(kids []*peg.Node, opts diagramOptions) []*diagramNode {
+	var ds []*diagramNode
+	for _, k := range kids {
+		switch {
+		case opts.showElided && parser.IsElidedTerminator(k):
+			ds = append(ds, diagram(k, opts))
+		case k.Text == "":
+			continue
+		case k.Name == "" && len(k.Kids) > 0:
+			ds = append(ds, diagramKids(k.Kids, opts)...)
+		default:
+			ds = append(ds, diagram(k, opts))
+		}
+	}
+	return ds
+}
+
+// Dot writes the tree in the Graphviz DOT language.
+// Rule names are the internal nodes of the graph and words are its leaves.
+func Dot(w io.Writer, n *peg.Node) error {
+	var b strings.Builder
+	b.WriteString("digraph tree {\n\tordering=out;\n\tnode [shape=plaintext];\n")
+	var id int
+	var walk func(*diagramNode) int
+	walk = func(d *diagramNode) int {
+		me := id
+		id++
+		attrs := ""
+		if d.word {
+			attrs = `, fontname="Times-Italic"`
+		}
+		fmt.Fprintf(&b, "\tn%d [label=%s%s];\n", me, dotQuote(d.label), attrs)
+		for _, k := range d.kids {
+			fmt.Fprintf(&b, "\tn%d -> n%d;\n", me, walk(k))
+		}
+		return me
+	}
+	walk(diagram(n, diagramOptions{}))
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// Sizes of SVG diagrams in pixels.
+const (
+	svgFontSize  = 14
+	svgRuneWidth = 0.6 * svgFontSize
+	svgLevel     = 3 * svgFontSize
+	svgGap       = svgFontSize
+	svgMargin    = svgFontSize
+)
+
+// SVG writes the tree as an SVG image of a tree diagram,
+// with rule names as the internal nodes and words as the leaves.
+func SVG(w io.Writer, n *peg.Node) error {
+	d := diagram(n, diagramOptions{})
+	depth := measure(d, 0)
+	place(d, 0)
+	width := d.width + 2*svgMargin
+	height := float64(depth)*svgLevel + svgFontSize + 2*svgMargin
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%.1f" height="%.1f" viewBox="0 0 %.1f %.1f">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, "<style>text { font-family: serif; font-size: %dpx; text-anchor: middle; } "+
+		".word { font-style: italic; } line { stroke: black; }</style>\n", svgFontSize)
+	var walk func(*diagramNode)
+	walk = func(d *diagramNode) {
+		x, y := svgMargin+d.x, svgMargin+float64(d.y)*svgLevel+svgFontSize
+		class := ""
+		if d.word {
+			class = ` class="word"`
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f"%s>%s</text>`+"\n", x, y, class, html.EscapeString(d.label))
+		for _, k := range d.kids {
+			kx, ky := svgMargin+k.x, svgMargin+float64(k.y)*svgLevel
+			fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"/>`+"\n", x, y+svgFontSize/3, kx, ky)
+			walk(k)
+		}
+	}
+	walk(d)
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// measure sets the depth and width of the node and its kids
+// and returns the maximum depth of the subtree.
+// The width of a subtree is the larger of the width of its label
+// and the sum of the widths of its kids' subtrees.
+func measure(d *diagramNode, depth int) int {
+	d.y = depth
+	d.width = float64(utf8.RuneCountInString(d.label)) * svgRuneWidth
+	var kidsWidth float64
+	max := depth
+	for i, k := range d.kids {
+		if i > 0 {
+			kidsWidth += svgGap
+		}
+		if m := measure(k, depth+1); m > max {
+			max = m
+		}
+		kidsWidth += k.width
+	}
+	if kidsWidth > d.width {
+		d.width = kidsWidth
+	}
+	return max
+}
+
+// place sets the horizontal position of the node and its kids,
+// placing the subtree to the right of left.
+// The kids are centered within the subtree,
+// and each node is centered above its first and last kids.
+func place(d *diagramNode, left float64) {
+	if len(d.kids) == 0 {
+		d.x = left + d.width/2
+		return
+	}
+	kidsWidth := float64(len(d.kids)-1) * svgGap
+	for _, k := range d.kids {
+		kidsWidth += k.width
+	}
+	x := left + (d.width-kidsWidth)/2
+	for _, k := range d.kids {
+		place(k, x)
+		x += k.width + svgGap
+	}
+	d.x = (d.kids[0].x + d.kids[len(d.kids)-1].x) / 2
+}
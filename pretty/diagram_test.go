@@ -0,0 +1,88 @@
+package pretty_test
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"within.website/johaus/pretty"
+)
+
+func TestDot(t *testing.T) {
+	var b strings.Builder
+	if err := pretty.Dot(&b, simplified(t, `zoi gy "\ gy cu valsi`)); err != nil {
+		t.Fatalf("Dot failed: %v", err)
+	}
+	const want = `digraph tree {
+	ordering=out;
+	node [shape=plaintext];
+	n0 [label="text_eof"];
+	n1 [label="ZOI_pre"];
+	n2 [label="ZOI"];
+	n3 [label="zoi", fontname="Times-Italic"];
+	n2 -> n3;
+	n1 -> n2;
+	n4 [label="CMAVO"];
+	n5 [label="gy", fontname="Times-Italic"];
+	n4 -> n5;
+	n1 -> n4;
+	n6 [label="zoi_word"];
+	n7 [label="\"\\", fontname="Times-Italic"];
+	n6 -> n7;
+	n1 -> n6;
+	n8 [label="CMAVO"];
+	n9 [label="gy", fontname="Times-Italic"];
+	n8 -> n9;
+	n1 -> n8;
+	n0 -> n1;
+	n10 [label="CU"];
+	n11 [label="cu", fontname="Times-Italic"];
+	n10 -> n11;
+	n0 -> n10;
+	n12 [label="BRIVLA"];
+	n13 [label="valsi", fontname="Times-Italic"];
+	n12 -> n13;
+	n0 -> n12;
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("Dot=\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	var b strings.Builder
+	if err := pretty.SVG(&b, simplified(t, "lo gerku cu klama zoi gy <&> gy")); err != nil {
+		t.Fatalf("SVG failed: %v", err)
+	}
+	var svg struct {
+		Width  float64 `xml:"width,attr"`
+		Height float64 `xml:"height,attr"`
+		Texts  []struct {
+			X     float64 `xml:"x,attr"`
+			Y     float64 `xml:"y,attr"`
+			Class string  `xml:"class,attr"`
+			Text  string  `xml:",chardata"`
+		} `xml:"text"`
+		Lines []struct{} `xml:"line"`
+	}
+	if err := xml.Unmarshal([]byte(b.String()), &svg); err != nil {
+		t.Fatalf("SVG is not valid XML: %v\n%s", err, b.String())
+	}
+	var words []string
+	for _, text := range svg.Texts {
+		if text.X <= 0 || text.X >= svg.Width || text.Y <= 0 || text.Y >= svg.Height {
+			t.Errorf("%q is at (%.1f, %.1f), outside the %.1f×%.1f image", text.Text, text.X, text.Y, svg.Width, svg.Height)
+		}
+		if text.Class == "word" {
+			words = append(words, text.Text)
+		}
+	}
+	// The words are the leaves, in order, and each other node has a line to each kid.
+	if got, want := strings.Join(words, " "), "lo gerku KU cu klama zoi gy <&> gy VAU"; got != want {
+		t.Errorf("SVG words=%q, want %q", got, want)
+	}
+	if len(svg.Lines) != len(svg.Texts)-1 {
+		t.Errorf("SVG has %d lines for %d nodes, want %d", len(svg.Lines), len(svg.Texts), len(svg.Texts)-1)
+	}
+}
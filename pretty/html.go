@@ -0,0 +1,148 @@
+package pretty
+
+import (
+	"html"
+	"io"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+)
+
+// HTML writes the tree as HTML nested boxes, one for each named node,
+// styled by HTMLStyle.
+//
+// The whole tree is a div with the class johaus-tree.
+// Each named node is a span with the class johaus-node
+// and a class johaus-<rule> naming its grammar construct, as by parser.Rule,
+// with _ replaced by -,
+// such as johaus-sumti for sumti_6, johaus-bridi-tail for bridi_tail_3,
+// or johaus-KU for KU_clause.
+// Each word is a span with the class johaus-word
+// and one of johaus-brivla, johaus-cmevla, johaus-cmavo,
+// or johaus-foreign for the text of a zoi quotation;
+// elided terminators added by parser.AddElidedTerminators
+// also have the class johaus-elided.
+// The title of each box, shown on hover, is the full rule name.
+func HTML(w io.Writer, n *peg.Node) error {
+	var b strings.Builder
+	b.WriteString(`<div class="johaus-tree">`)
+	htmlNode(&b, n)
+	b.WriteString("</div>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func htmlNode(b *strings.Builder, n *peg.Node) {
+	switch {
+	case parser.IsElidedTerminator(n):
+		if n.Text != "" {
+			htmlSpan(b, "johaus-word johaus-cmavo johaus-elided", n.Name, n.Text)
+		}
+	case n.Text == "":
+		return
+	case n.Name == "" && len(n.Kids) == 0:
+		b.WriteString(html.EscapeString(n.Text))
+	case n.Name == "":
+		htmlKids(b, n)
+	case len(n.Kids) == 0 || parser.IsWord(n):
+		htmlSpan(b, "johaus-word "+wordClass(n.Name), n.Name, n.Text)
+	default:
+		b.WriteString(`<span class="johaus-node ` + ruleClass(n.Name) + `" title="` + html.EscapeString(n.Name) + `">`)
+		htmlKids(b, n)
+		b.WriteString("</span>")
+	}
+}
+
+func htmlKids(b *strings.Builder, n *peg.Node) {
+	sep := ""
+	for _, k := range n.Kids {
+		if k.Text == "" {
+			continue
+		}
+		b.WriteString(sep)
+		htmlNode(b, k)
+		sep = " "
+	}
+}
+
+func htmlSpan(b *strings.Builder, class, title, text string) {
+	b.WriteString(`<span class="` + class + `" title="` + html.EscapeString(title) + `">` + html.EscapeString(text) + "</span>")
+}
+
+// wordClass returns the class of a word with the rule name.
+func wordClass(name string) string {
+	switch name {
+	case "BRIVLA":
+		return "johaus-brivla"
+	case "CMEVLA":
+		return "johaus-cmevla"
+	case "zoi_word":
+		return "johaus-foreign"
+	}
+	return "johaus-cmavo"
+}
+
+// ruleClass returns the class of a node with the rule name.
+func ruleClass(name string) string {
+	return "johaus-" + strings.Replace(parser.Rule(name), "_", "-", -1)
+}
+
+// HTMLStyle is a CSS stylesheet for the HTML printer's output.
+const HTMLStyle = `
+.johaus-tree {
+	font-family: sans-serif;
+	line-height: 2.2;
+}
+.johaus-node {
+	display: inline-block;
+	margin: 2px;
+	padding: 0 4px;
+	border: 1px solid #bdbdbd;
+	border-radius: 4px;
+	vertical-align: middle;
+}
+.johaus-node:hover {
+	border-color: #424242;
+}
+.johaus-sentence {
+	background-color: #fafafa;
+}
+.johaus-sumti, .johaus-sumti-tail {
+	background-color: #e3f2fd;
+	border-color: #64b5f6;
+}
+.johaus-selbri, .johaus-tanru-unit {
+	background-color: #e8f5e9;
+	border-color: #81c784;
+}
+.johaus-bridi-tail {
+	background-color: #fff8e1;
+	border-color: #ffd54f;
+}
+.johaus-tag, .johaus-stag, .johaus-tense-modal {
+	background-color: #f3e5f5;
+	border-color: #ba68c8;
+}
+.johaus-free, .johaus-vocative, .johaus-indicators {
+	background-color: #fbe9e7;
+	border-color: #ff8a65;
+}
+.johaus-relative-clause, .johaus-relative-clauses {
+	background-color: #e0f2f1;
+	border-color: #4db6ac;
+}
+.johaus-brivla {
+	font-weight: bold;
+}
+.johaus-cmevla {
+	font-style: italic;
+}
+.johaus-foreign {
+	font-family: monospace;
+}
+.johaus-elided {
+	color: #9e9e9e;
+	font-size: smaller;
+}
+`
@@ -0,0 +1,77 @@
+package pretty_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
+	_ "within.website/johaus/parser/alldialects"
+	"within.website/johaus/pretty"
+)
+
+// simplified returns the camxes parse tree of the text
+// simplified as by the johaus command with -t.
+func simplified(t *testing.T, text string) *peg.Node {
+	t.Helper()
+	tree, err := parser.Parse("camxes", text)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", text, err)
+	}
+	parser.RemoveMorphology(tree)
+	parser.AddElidedTerminators(tree)
+	parser.RemoveSpace(tree)
+	parser.CollapseLists(tree)
+	return tree
+}
+
+func TestHTML(t *testing.T) {
+	var b strings.Builder
+	if err := pretty.HTML(&b, simplified(t, "mi klama")); err != nil {
+		t.Fatalf("HTML failed: %v", err)
+	}
+	const want = `<div class="johaus-tree">` +
+		`<span class="johaus-node johaus-text-eof" title="text_eof">` +
+		`<span class="johaus-word johaus-cmavo" title="KOhA">mi</span> ` +
+		`<span class="johaus-word johaus-cmavo johaus-elided" title="CU_elidible">CU</span> ` +
+		`<span class="johaus-word johaus-brivla" title="BRIVLA">klama</span>` +
+		"</span></div>\n"
+	if got := b.String(); got != want {
+		t.Errorf("HTML=\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHTMLClasses(t *testing.T) {
+	tree := &peg.Node{Name: "bridi_tail_t1", Text: "mi zoi gy <&> gy", Kids: []*peg.Node{
+		{Name: "sumti_6", Text: "mi", Kids: []*peg.Node{
+			{Name: "KOhA_clause", Text: "mi", Kids: []*peg.Node{
+				{Name: "KOhA_pre", Text: "mi", Kids: []*peg.Node{
+					{Name: "KOhA", Text: "mi"},
+				}},
+			}},
+		}},
+		{Name: "ZOI_pre", Text: "zoi gy <&> gy", Kids: []*peg.Node{
+			{Name: "ZOI", Text: "zoi"},
+			{Name: "CMAVO", Text: "gy"},
+			{Name: "zoi_word", Text: "<&>"},
+			{Name: "CMAVO", Text: "gy"},
+		}},
+	}}
+	var b strings.Builder
+	if err := pretty.HTML(&b, tree); err != nil {
+		t.Fatalf("HTML failed: %v", err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		`<span class="johaus-node johaus-bridi-tail" title="bridi_tail_t1">`,
+		`<span class="johaus-node johaus-sumti" title="sumti_6">`,
+		`<span class="johaus-node johaus-KOhA" title="KOhA_clause">`,
+		`<span class="johaus-node johaus-KOhA-pre" title="KOhA_pre">`,
+		`<span class="johaus-node johaus-ZOI-pre" title="ZOI_pre">`,
+		`<span class="johaus-word johaus-foreign" title="zoi_word">&lt;&amp;&gt;</span>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML=\n%s\nwant it to contain\n%s", got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package pretty
+
+import (
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"within.website/johaus/gloss"
+)
+
+// Interlinear writes the glosses as aligned interlinear text,
+// three lines for each sentence: the words, their selma'o, and their glosses,
+// with each word aligned above its selma'o and gloss,
+// and its groups, the sumti and selbri, separated by |:
+//
+//	mi   | klama  | lo zarci
+//	KOhA | BRIVLA | LE BRIVLA
+//	me   | come   |    store
+//
+// A word with no gloss, such as lo here, has a blank gloss.
+// Sentences are separated by a blank line.
+func Interlinear(w io.Writer, ss []gloss.Sentence) error {
+	var b strings.Builder
+	for i, s := range ss {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		var lines [3]strings.Builder
+		for j, g := range s.Groups {
+			for k, word := range g.Words {
+				sep := " "
+				switch {
+				case j == 0 && k == 0:
+					sep = ""
+				case k == 0:
+					sep = " | "
+				}
+				cells := [3]string{word.Text, word.Selmaho, word.Gloss}
+				width := 0
+				for _, c := range cells {
+					if n := utf8.RuneCountInString(c); n > width {
+						width = n
+					}
+				}
+				for l, c := range cells {
+					lines[l].WriteString(sep + c + strings.Repeat(" ", width-utf8.RuneCountInString(c)))
+				}
+			}
+		}
+		for _, l := range lines {
+			b.WriteString(strings.TrimRight(l.String(), " ") + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// InterlinearHTML writes the glosses as HTML, styled by InterlinearStyle.
+//
+// The glosses are a div with the class johaus-gloss,
+// and each sentence a div with the class johaus-gloss-sentence.
+// Each group is a span with the class johaus-gloss-group
+// and johaus-gloss-sumti or johaus-gloss-selbri for a sumti or selbri.
+// Each word is a span with the class johaus-gloss-word
+// of three spans, one for each line of the gloss,
+// with the classes johaus-gloss-text, johaus-gloss-selmaho, and johaus-gloss-gloss.
+func InterlinearHTML(w io.Writer, ss []gloss.Sentence) error {
+	var b strings.Builder
+	b.WriteString(`<div class="johaus-gloss">`)
+	for _, s := range ss {
+		b.WriteString(`<div class="johaus-gloss-sentence">`)
+		for _, g := range s.Groups {
+			class := "johaus-gloss-group"
+			if g.Kind != "" {
+				class += " johaus-gloss-" + g.Kind
+			}
+			b.WriteString(`<span class="` + class + `">`)
+			for _, word := range g.Words {
+				b.WriteString(`<span class="johaus-gloss-word">`)
+				b.WriteString(`<span class="johaus-gloss-text">` + html.EscapeString(word.Text) + "</span>")
+				b.WriteString(`<span class="johaus-gloss-selmaho">` + html.EscapeString(word.Selmaho) + "</span>")
+				b.WriteString(`<span class="johaus-gloss-gloss">` + html.EscapeString(word.Gloss) + "</span>")
+				b.WriteString("</span>")
+			}
+			b.WriteString("</span>")
+		}
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// InterlinearStyle is a CSS stylesheet for the InterlinearHTML printer's output.
+const InterlinearStyle = `
+.johaus-gloss {
+	font-family: sans-serif;
+}
+.johaus-gloss-sentence {
+	margin-bottom: 1em;
+}
+.johaus-gloss-group {
+	display: inline-block;
+	margin: 2px;
+	padding: 0 4px;
+	border: 1px solid transparent;
+	border-radius: 4px;
+	vertical-align: top;
+}
+.johaus-gloss-sumti {
+	background-color: #e3f2fd;
+	border-color: #64b5f6;
+}
+.johaus-gloss-selbri {
+	background-color: #e8f5e9;
+	border-color: #81c784;
+}
+.johaus-gloss-word {
+	display: inline-block;
+	margin-right: 0.5em;
+	vertical-align: top;
+}
+.johaus-gloss-word:last-child {
+	margin-right: 0;
+}
+.johaus-gloss-word > span {
+	display: block;
+	min-height: 1.2em;
+}
+.johaus-gloss-text {
+	font-weight: bold;
+}
+.johaus-gloss-selmaho {
+	font-size: smaller;
+	color: #616161;
+}
+.johaus-gloss-gloss {
+	font-style: italic;
+}
+`
@@ -0,0 +1,128 @@
+package pretty
+
+import (
+	"io"
+	"strings"
+
+	"github.com/eaburns/peggy/peg"
+)
+
+// A LaTeXFormat is the syntax of a LaTeX tree-drawing package.
+type LaTeXFormat int
+
+const (
+	// Forest is the syntax of the forest package.
+	Forest LaTeXFormat = iota
+
+	// Qtree is the syntax of the qtree package.
+	Qtree
+)
+
+// LaTeXOptions are options for LaTeX.
+type LaTeXOptions struct {
+	// Format is the syntax of the tree.
+	Format LaTeXFormat
+
+	// HideMorphology is whether to draw whole words as leaves,
+	// omitting the nodes beneath them.
+	HideMorphology bool
+
+	// ShowElided is whether to draw elided terminators
+	// as empty categories, their rule name above ∅.
+	// Elided terminators are kept by the tree
+	// only if it was first passed to parser.AddElidedTerminators.
+	ShowElided bool
+}
+
+// LaTeX writes the tree as a LaTeX tree diagram,
+// with rule names as the internal nodes and words as the leaves.
+// Text is escaped for LaTeX,
+// so apostrophes and the arbitrary text of zoi quotations print as written.
+func LaTeX(w io.Writer, n *peg.Node, opts LaTeXOptions) error {
+	d := diagram(n, diagramOptions{
+		hideMorphology: opts.HideMorphology,
+		showElided:     opts.ShowElided,
+	})
+	var b strings.Builder
+	switch opts.Format {
+	case Qtree:
+		b.WriteString(`\Tree `)
+		qtree(&b, "", d)
+	default:
+		b.WriteString("\\begin{forest}\n")
+		forest(&b, "", d)
+		b.WriteString("\\end{forest}\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func forest(b *strings.Builder, tab string, d *diagramNode) {
+	b.WriteString(tab + "[{" + latexLabel(d) + "}")
+	if len(d.kids) == 1 && len(d.kids[0].kids) == 0 {
+		b.WriteString(" [{" + latexLabel(d.kids[0]) + "}]]\n")
+		return
+	}
+	b.WriteString("\n")
+	for _, k := range d.kids {
+		forest(b, tab+"\t", k)
+	}
+	b.WriteString(tab + "]\n")
+}
+
+func qtree(b *strings.Builder, tab string, d *diagramNode) {
+	if len(d.kids) == 0 {
+		b.WriteString("{" + latexLabel(d) + "}")
+		return
+	}
+	b.WriteString("[.{" + latexLabel(d) + "}")
+	if len(d.kids) == 1 && len(d.kids[0].kids) == 0 {
+		b.WriteString(" {" + latexLabel(d.kids[0]) + "} ]")
+		return
+	}
+	for _, k := range d.kids {
+		b.WriteString("\n" + tab + "\t")
+		qtree(b, tab+"\t", k)
+	}
+	b.WriteString("\n" + tab + "]")
+	if tab == "" {
+		b.WriteString("\n")
+	}
+}
+
+func latexLabel(d *diagramNode) string {
+	switch {
+	case d.empty:
+		return `$\emptyset$`
+	case d.word:
+		return `\textit{` + latexEscape(d.label) + `}`
+	}
+	return latexEscape(d.label)
+}
+
+var latexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`,
+	`{`, `\{`,
+	`}`, `\}`,
+	`$`, `\$`,
+	`&`, `\&`,
+	`#`, `\#`,
+	`%`, `\%`,
+	`_`, `\_`,
+	`^`, `\textasciicircum{}`,
+	`~`, `\textasciitilde{}`,
+	`<`, `\textless{}`,
+	`>`, `\textgreater{}`,
+	`|`, `\textbar{}`,
+	`"`, `\textquotedbl{}`,
+	// Lojban apostrophes are straight, not closing quotes.
+	`'`, `\textquotesingle{}`,
+	// Quote the marks that LaTeX joins into ligatures, such as -- and ``.
+	"`", `\textasciigrave{}`,
+	`-`, `{-}`,
+	"\n", ` `,
+)
+
+func latexEscape(s string) string {
+	return latexEscaper.Replace(s)
+}
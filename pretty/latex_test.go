@@ -0,0 +1,69 @@
+package pretty_test
+
+import (
+	"strings"
+	"testing"
+
+	"within.website/johaus/pretty"
+)
+
+func TestLaTeX(t *testing.T) {
+	tests := []struct {
+		text string
+		opts pretty.LaTeXOptions
+		want string
+	}{
+		{
+			text: "mi klama",
+			opts: pretty.LaTeXOptions{HideMorphology: true, ShowElided: true},
+			want: `\begin{forest}
+[{text\_eof}
+	[{KOhA} [{\textit{mi}}]]
+	[{CU\_elidible} [{$\emptyset$}]]
+	[{BRIVLA} [{\textit{klama}}]]
+]
+\end{forest}
+`,
+		},
+		{
+			text: "mi klama",
+			opts: pretty.LaTeXOptions{Format: pretty.Qtree, HideMorphology: true, ShowElided: true},
+			want: `\Tree [.{text\_eof}
+	[.{KOhA} {\textit{mi}} ]
+	[.{CU\_elidible} {$\emptyset$} ]
+	[.{BRIVLA} {\textit{klama}} ]
+]
+`,
+		},
+		{
+			text: "zoi gy $x_1 & {y}' -- gy cu valsi",
+			opts: pretty.LaTeXOptions{HideMorphology: true},
+			want: `\begin{forest}
+[{text\_eof}
+	[{ZOI\_pre}
+		[{ZOI} [{\textit{zoi}}]]
+		[{CMAVO} [{\textit{gy}}]]
+		[{zoi\_word} [{\textit{\$x\_1}}]]
+		[{zoi\_word} [{\textit{\&}}]]
+		[{zoi\_word} [{\textit{\{y\}\textquotesingle{}}}]]
+		[{zoi\_word} [{\textit{{-}{-}}}]]
+		[{CMAVO} [{\textit{gy}}]]
+	]
+	[{CU} [{\textit{cu}}]]
+	[{BRIVLA} [{\textit{valsi}}]]
+]
+\end{forest}
+`,
+		},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		if err := pretty.LaTeX(&b, simplified(t, test.text), test.opts); err != nil {
+			t.Errorf("LaTeX(%q, %+v) failed: %v", test.text, test.opts, err)
+			continue
+		}
+		if got := b.String(); got != test.want {
+			t.Errorf("LaTeX(%q, %+v)=\n%s\nwant\n%s", test.text, test.opts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package pretty
+
+import (
+	"io"
+	"strings"
+
+	"within.website/johaus/logic"
+)
+
+// Logic writes the formulas, one on each line, in the notation of first-order logic:
+//
+//	∀da (klama(da, lo zarci) ∨ ¬citka(da, zo'e))
+//
+// Constants are written as in the text.
+func Logic(w io.Writer, fs []*logic.Formula) error {
+	var b strings.Builder
+	for _, f := range fs {
+		formula(&b, f, logicText)
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// LogicLaTeX writes the formulas as LaTeX, one display-math environment for each.
+// Predicates and constants are upright, and variables are italic:
+//
+//	\[ \forall \textit{da}\, \text{klama}(\textit{da}, \text{lo zarci}) \]
+//
+// The \text command is from the amsmath package.
+func LogicLaTeX(w io.Writer, fs []*logic.Formula) error {
+	var b strings.Builder
+	for _, f := range fs {
+		b.WriteString(`\[ `)
+		formula(&b, f, logicLaTeX)
+		b.WriteString(" \\]\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// A logicNotation is the symbols of a notation of formulas.
+type logicNotation struct {
+	not, and, or, iff, exists, forAll string
+
+	// quantified separates the variable of a quantifier from its operand.
+	quantified string
+
+	// predicate, constant, and variable return the text of a name.
+	predicate, constant, variable func(string) string
+}
+
+var logicText = logicNotation{
+	not:        "¬",
+	and:        " ∧ ",
+	or:         " ∨ ",
+	iff:        " ↔ ",
+	exists:     "∃",
+	forAll:     "∀",
+	quantified: " ",
+	predicate:  func(s string) string { return s },
+	constant:   func(s string) string { return s },
+	variable:   func(s string) string { return s },
+}
+
+var logicLaTeX = logicNotation{
+	not:        `\lnot `,
+	and:        ` \land `,
+	or:         ` \lor `,
+	iff:        ` \leftrightarrow `,
+	exists:     `\exists `,
+	forAll:     `\forall `,
+	quantified: `\, `,
+	predicate:  func(s string) string { return `\text{` + latexEscape(s) + `}` },
+	constant:   func(s string) string { return `\text{` + latexEscape(s) + `}` },
+	variable:   func(s string) string { return `\textit{` + latexEscape(s) + `}` },
+}
+
+// formula writes the formula in the notation.
+// The operands of a connective are in parentheses unless they are atoms or their negations,
+// and so is the operand of a negation or quantifier that is a connective.
+func formula(b *strings.Builder, f *logic.Formula, n logicNotation) {
+	switch f.Kind {
+	case logic.Atom:
+		b.WriteString(n.predicate(f.Predicate))
+		if len(f.Args) == 0 {
+			return
+		}
+		b.WriteString("(")
+		for i, a := range f.Args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if a.Variable {
+				b.WriteString(n.variable(a.Name))
+			} else {
+				b.WriteString(n.constant(a.Name))
+			}
+		}
+		b.WriteString(")")
+	case logic.Not:
+		b.WriteString(n.not)
+		operand(b, f.Operands[0], n, isConnection(f.Operands[0]))
+	case logic.Exists, logic.ForAll:
+		if f.Kind == logic.Exists {
+			b.WriteString(n.exists)
+		} else {
+			b.WriteString(n.forAll)
+		}
+		b.WriteString(n.variable(f.Var) + n.quantified)
+		operand(b, f.Operands[0], n, isConnection(f.Operands[0]))
+	default:
+		op := map[logic.Kind]string{logic.And: n.and, logic.Or: n.or, logic.Iff: n.iff}[f.Kind]
+		operand(b, f.Operands[0], n, !isLiteral(f.Operands[0]))
+		b.WriteString(op)
+		operand(b, f.Operands[1], n, !isLiteral(f.Operands[1]))
+	}
+}
+
+func operand(b *strings.Builder, f *logic.Formula, n logicNotation, paren bool) {
+	if paren {
+		b.WriteString("(")
+	}
+	formula(b, f, n)
+	if paren {
+		b.WriteString(")")
+	}
+}
+
+func isConnection(f *logic.Formula) bool {
+	switch f.Kind {
+	case logic.And, logic.Or, logic.Iff:
+		return true
+	}
+	return false
+}
+
+// isLiteral returns whether the formula is an atom or the negation of a literal.
+func isLiteral(f *logic.Formula) bool {
+	for f.Kind == logic.Not {
+		f = f.Operands[0]
+	}
+	return f.Kind == logic.Atom
+}
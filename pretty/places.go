@@ -0,0 +1,122 @@
+package pretty
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/ast"
+	"within.website/johaus/dictionary"
+	"within.website/johaus/parser"
+	"within.website/johaus/places"
+)
+
+// Places writes the place assignments of the bridi,
+// with the keywords of the places from the dictionary, which may be nil.
+// Each bridi is its sentence, or its description,
+// followed by a line for its selbri and a line for each of its terms:
+// the place it fills, or its tag, the term,
+// and the place of the word that it fills, with its keyword:
+//
+//	mi se klama lo zarci pi'o lo karce
+//	  selbri  se klama
+//	  x1      mi        klama x2 destination
+//	  x2      lo zarci  klama x1 comer
+//	  pi'o    lo karce
+//
+// Bridi are separated by a blank line.
+func Places(w io.Writer, bs []places.Bridi, dict *dictionary.Dictionary) error {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+	for i, br := range bs {
+		if i > 0 {
+			io.WriteString(tw, "\n")
+		}
+		if br.Sentence != nil {
+			io.WriteString(tw, unparse(br.Sentence.Tree)+"\n")
+		} else {
+			io.WriteString(tw, unparse(br.Description.Tree)+"\n")
+		}
+		io.WriteString(tw, "\tselbri\t"+unparse(br.Selbri.Tree)+"\t\n")
+		for _, p := range br.Places {
+			label, term := placeLabel(p.Term), unparse(p.Term.Tree)
+			switch {
+			case p.Place > 0:
+				label = "x" + strconv.Itoa(p.Place)
+			case p.Term.Sumti != nil:
+				term = unparse(p.Term.Sumti.Tree)
+			default:
+				term = ""
+			}
+			io.WriteString(tw, "\t"+label+"\t"+term+"\t"+placeNote(p, dict)+"\n")
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	var out strings.Builder
+	for _, l := range strings.SplitAfter(b.String(), "\n") {
+		out.WriteString(strings.TrimRight(l, " \n"))
+		if strings.HasSuffix(l, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// placeLabel returns the label of a term that fills no place:
+// its tag, its fi'a, or its na ku.
+func placeLabel(t *ast.Term) string {
+	switch {
+	case t.Tag != nil:
+		return unparse(t.Tag.Tree)
+	case t.FA != "":
+		return strings.ToLower(t.FA)
+	case t.NA != "":
+		return strings.ToLower(t.NA) + " ku"
+	}
+	return ""
+}
+
+// placeNote returns the place of the word filled by a place,
+// such as klama x2 destination.
+func placeNote(p places.Place, dict *dictionary.Dictionary) string {
+	if p.Unit == nil || p.Place == 0 {
+		return ""
+	}
+	word := unitWord(p.Unit)
+	if p.Underlying == 0 {
+		convs := strings.ToLower(strings.Join(p.Unit.Conversions, " "))
+		return strings.TrimSpace(convs+" "+word) + " x" + strconv.Itoa(p.Place)
+	}
+	note := word + " x" + strconv.Itoa(p.Underlying)
+	if dict == nil {
+		return note
+	}
+	if e, ok := dict.Word(word); ok && p.Underlying <= len(e.Places) && e.Places[p.Underlying-1] != "" {
+		note += " " + e.Places[p.Underlying-1]
+	}
+	return note
+}
+
+// unitWord returns the word of a tanru unit whose places are filled,
+// such as klama, or nu for an abstraction.
+func unitWord(u *ast.TanruUnit) string {
+	switch {
+	case u.Word != "":
+		return strings.ToLower(u.Word)
+	case len(u.Abstractor) > 0:
+		return strings.ToLower(strings.Join(u.Abstractor, " "))
+	}
+	return unparse(u.Tree)
+}
+
+func unparse(n *peg.Node) string {
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(parser.UnparseOptions{Normalize: true}.Unparse(n))
+}
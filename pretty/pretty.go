@@ -8,6 +8,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/parser"
 )
 
 var braces = [][2]string{
@@ -19,27 +20,41 @@ var braces = [][2]string{
 
 // Braces pretty-prints a parse tree using ( ), [ ], { }, and < > show the nesting structure.
 func Braces(w io.Writer, n *peg.Node) error {
+	return printBraces(w, n, false)
+}
+
+// ColorBraces is like Braces, but it colors the output with ANSI escape sequences.
+// Brackets are colored by depth, cycling through six colors,
+// so that each combination of bracket and color repeats only every twelve levels.
+// Words are colored by class: selma'o, brivla, or cmevla.
+// Elided terminators added by parser.AddElidedTerminators are dimmed.
+func ColorBraces(w io.Writer, n *peg.Node) error {
+	return printBraces(w, n, true)
+}
+
+func printBraces(w io.Writer, n *peg.Node, color bool) error {
 	var walk func(int, *peg.Node) error
 	walk = func(depth int, n *peg.Node) error {
 		if n.Text == "" {
 			return nil
 		}
 		if len(n.Kids) == 0 {
-			_, err := io.WriteString(w, n.Text)
+			_, err := io.WriteString(w, colorize(color, leafColor(n), n.Text))
 			return err
 		}
-		if _, err := io.WriteString(w, braces[depth][0]); err != nil {
+		bracketColor := depthColors[depth%len(depthColors)]
+		if _, err := io.WriteString(w, colorize(color, bracketColor, braces[depth%len(braces)][0])); err != nil {
 			return err
 		}
 		for i, kid := range n.Kids {
-			walk((depth+1)%len(braces), kid)
+			walk(depth+1, kid)
 			if i != len(n.Kids)-1 {
 				if _, err := io.WriteString(w, " "); err != nil {
 					return err
 				}
 			}
 		}
-		_, err := io.WriteString(w, braces[depth][1])
+		_, err := io.WriteString(w, colorize(color, bracketColor, braces[depth%len(braces)][1]))
 		return err
 	}
 	return walk(0, n)
@@ -48,12 +63,19 @@ func Braces(w io.Writer, n *peg.Node) error {
 // Tree writes a pretty representation of the tree.
 func Tree(w io.Writer, n *peg.Node) error {
 	nr := utf8.RuneCountInString(n.Name)
-	return tree(w, "", nr, n)
+	return tree(w, "", nr, n, false)
 }
 
-func tree(w io.Writer, tab string, nameWidth int, n *peg.Node) error {
+// ColorTree is like Tree, but it colors the leaves with ANSI escape sequences
+// like ColorBraces.
+func ColorTree(w io.Writer, n *peg.Node) error {
+	nr := utf8.RuneCountInString(n.Name)
+	return tree(w, "", nr, n, true)
+}
+
+func tree(w io.Writer, tab string, nameWidth int, n *peg.Node, color bool) error {
 	if len(n.Kids) == 0 {
-		_, err := io.WriteString(w, n.Name+"["+strconv.Quote(n.Text)+"]\n")
+		_, err := io.WriteString(w, colorize(color, leafColor(n), n.Name+"["+strconv.Quote(n.Text)+"]")+"\n")
 		return err
 	}
 	name := n.Name
@@ -88,9 +110,53 @@ func tree(w io.Writer, tab string, nameWidth int, n *peg.Node) error {
 		if _, err := io.WriteString(w, kidLine); err != nil {
 			return err
 		}
-		if err := tree(w, kidTab, kidNameWidth, kid); err != nil {
+		if err := tree(w, kidTab, kidNameWidth, kid, color); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// ANSI escape sequences.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiDim    = "\x1b[2m"
+	ansiGreen  = "\x1b[32m"
+	ansiPurple = "\x1b[35m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// depthColors are the bright colors of brackets by depth.
+var depthColors = []string{
+	"\x1b[91m", // red
+	"\x1b[93m", // yellow
+	"\x1b[92m", // green
+	"\x1b[96m", // cyan
+	"\x1b[94m", // blue
+	"\x1b[95m", // magenta
+}
+
+// leafColor returns the ANSI color of a leaf node,
+// or the empty string if the leaf is not colored.
+func leafColor(n *peg.Node) string {
+	switch {
+	case parser.IsElidedTerminator(n):
+		return ansiDim
+	case n.Name == "BRIVLA":
+		return ansiGreen
+	case n.Name == "CMEVLA":
+		return ansiPurple
+	case n.Name != "zoi_word" && parser.IsWord(n):
+		return ansiCyan
+	}
+	return ""
+}
+
+// colorize returns the string with the ANSI color
+// if color is true and the ANSI color is not the empty string.
+func colorize(color bool, ansi, s string) string {
+	if !color || ansi == "" {
+		return s
+	}
+	return ansi + s + ansiReset
+}
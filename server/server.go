@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"html/template"
@@ -10,16 +11,43 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/eaburns/peggy/peg"
+	"within.website/johaus/dictionary"
+	"within.website/johaus/gloss"
+	"within.website/johaus/jsontree"
+	"within.website/johaus/logic"
 	"within.website/johaus/parser"
 	_ "within.website/johaus/parser/alldialects"
+	"within.website/johaus/parser/morph"
+	"within.website/johaus/places"
 	"within.website/johaus/pretty"
 )
 
+// parseTimeout is the maximum time to spend parsing a request's text.
+const parseTimeout = 10 * time.Second
+
+// parseOptions limits the size of the texts parsed by the server.
+var parseOptions = parser.Options{
+	MaxBytes:     16 << 10,
+	MaxMemoBytes: 256 << 20,
+}
+
+// dict is the dictionary loaded from the jbovlaste XML export at $JOHAUS_DICT,
+// or nil if it is not set.
+var dict *dictionary.Dictionary
+
 func init() {
+	if path := os.Getenv("JOHAUS_DICT"); path != "" {
+		var err error
+		if dict, err = dictionary.LoadFile(path); err != nil {
+			log.Fatal(err)
+		}
+	}
 	http.HandleFunc("/", rootHandler)
 }
 
@@ -43,7 +71,8 @@ func rootHandler(w http.ResponseWriter, req *http.Request) {
 		}
 		data := map[string]interface{}{
 			"Dialect":  dialect,
-			"Dialects": dialectNames,
+			"Dialects": parser.DialectNames(),
+			"Style":    template.CSS(pretty.HTMLStyle + pretty.InterlinearStyle),
 		}
 		if err := t.ExecuteTemplate(w, "parser.tmplt", data); err != nil {
 			http.Error(w, "", http.StatusInternalServerError)
@@ -55,22 +84,133 @@ func rootHandler(w http.ResponseWriter, req *http.Request) {
 			http.Error(w, "", http.StatusInternalServerError)
 			return
 		}
+		query := req.URL.Query()
+		if q := query["format"]; len(q) > 0 {
+			switch q[0] {
+			case "ipa":
+				ipaHandler(w, dialect.Name, string(text))
+				return
+			case "define":
+				defineHandler(w, dialect.Name, string(text))
+				return
+			}
+		}
+		opts := parseOptions
+		if q := query["errors"]; len(q) > 0 {
+			if opts.Errors, err = parser.ParseErrorMode(q[0]); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 		resp := make(map[string]interface{})
-		tree, err := parser.Parse(dialect.Name, string(text))
+		ctx, cancel := context.WithTimeout(req.Context(), parseTimeout)
+		defer cancel()
+		tree, err := parser.ParseContext(ctx, dialect.Name, string(text), opts)
+		if _, ok := err.(*parser.BudgetError); ok {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err == context.DeadlineExceeded {
+			http.Error(w, "parse timed out", http.StatusServiceUnavailable)
+			return
+		}
+		if ierr, ok := err.(*parser.InternalError); ok {
+			log.Printf("%s\n%s", ierr, prettyFail(ierr.Tree))
+		}
+		var format string
+		if q := query["format"]; len(q) > 0 {
+			format = q[0]
+		}
+		if diagram, ok := diagrams[format]; ok {
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			simplify(tree, query)
+			w.Header().Set("Content-Type", diagram.contentType)
+			diagram.print(w, tree)
+			return
+		}
+		if format == "gloss" {
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			parser.RemoveMorphology(tree)
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			pretty.InterlinearHTML(w, gloss.Gloss(dialect.Name, dict, tree))
+			return
+		}
+		if format == "places" {
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			parser.RemoveMorphology(tree)
+			bs, err := places.Assign(tree)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			pretty.Places(w, bs, dict)
+			return
+		}
+		if format == "logic" || format == "logic-latex" {
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			parser.RemoveMorphology(tree)
+			fs, err := logic.Translate(tree)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if format == "logic" {
+				pretty.Logic(w, fs)
+			} else {
+				pretty.LogicLaTeX(w, fs)
+			}
+			return
+		}
+		if format == "json" {
+			var res jsontree.Result
+			if err != nil {
+				var ok bool
+				if res.Errors, ok = jsontree.FromErrors(err); !ok {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			} else {
+				ranges := parser.Ranges(string(text), tree)
+				simplify(tree, query)
+				res.Tree = jsontree.FromTree(tree, ranges)
+			}
+			if err := jsontree.Encode(w, &res); err != nil {
+				http.Error(w, "", http.StatusInternalServerError)
+			}
+			return
+		}
+		switch err := err.(type) {
+		case *parser.Error:
+			if opts.Errors == parser.RawErrors {
+				resp["RawError"] = err
+			} else {
+				resp["WordError"] = err
+			}
+		case *parser.ErrorPair:
+			resp["WordError"] = err.Word
+			resp["RawError"] = err.Raw
+		}
 		if err != nil {
 			resp["Error"] = err.Error()
 		} else {
-			query := req.URL.Query()
-			if q := query["morph"]; len(q) < 1 || q[0] != "true" {
-				parser.RemoveMorphology(tree)
-			}
-			if q := query["terms"]; len(q) > 0 && q[0] == "true" {
-				parser.AddElidedTerminators(tree)
-			}
-			parser.RemoveSpace(tree)
-			parser.CollapseLists(tree)
+			simplify(tree, query)
 			resp["Tree"] = prettyString(pretty.Tree, tree)
 			resp["Braces"] = prettyString(pretty.Braces, tree)
+			resp["HTML"] = prettyString(pretty.HTML, tree)
 		}
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			http.Error(w, "", http.StatusInternalServerError)
@@ -83,12 +223,93 @@ func rootHandler(w http.ResponseWriter, req *http.Request) {
 
 }
 
+// ipaHandler writes the IPA transcription of the text.
+// The text is transcribed word by word, without parsing it,
+// so it is written even if the text does not parse.
+func ipaHandler(w http.ResponseWriter, dialect, text string) {
+	if len(text) > parseOptions.MaxBytes {
+		err := &parser.BudgetError{Budget: "MaxBytes", Limit: parseOptions.MaxBytes, Size: len(text)}
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	ipa, err := morph.IPA(dialect, text)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, ipa+"\n")
+}
+
+// defineHandler writes the dictionary entries of the words of the text as JSON:
+// a list of objects with the Word as written and its dictionary Entry,
+// which is null for words not in the dictionary.
+// Like ipaHandler, it does not parse the text.
+func defineHandler(w http.ResponseWriter, dialect, text string) {
+	if dict == nil {
+		http.Error(w, "no dictionary is loaded", http.StatusNotFound)
+		return
+	}
+	if len(text) > parseOptions.MaxBytes {
+		err := &parser.BudgetError{Budget: "MaxBytes", Limit: parseOptions.MaxBytes, Size: len(text)}
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	words, err := morph.Words(dialect, text)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	type definition struct {
+		Word  string
+		Entry *dictionary.Entry
+	}
+	defs := []definition{}
+	for _, word := range words {
+		if word.Class == morph.NonLojban || word.Class == morph.Foreign {
+			continue
+		}
+		e, _ := dict.Word(word.Text)
+		defs = append(defs, definition{Word: word.Text, Entry: e})
+	}
+	if err := json.NewEncoder(w).Encode(defs); err != nil {
+		http.Error(w, "", http.StatusInternalServerError)
+	}
+}
+
+// diagrams are the printers of the diagram formats, by the name of the format.
+var diagrams = map[string]struct {
+	contentType string
+	print       func(io.Writer, *peg.Node) error
+}{
+	"svg": {"image/svg+xml", pretty.SVG},
+	"dot": {"text/vnd.graphviz; charset=utf-8", pretty.Dot},
+}
+
+// simplify applies the simplifications selected by the query to the tree.
+func simplify(tree *peg.Node, query url.Values) {
+	if q := query["morph"]; len(q) < 1 || q[0] != "true" {
+		parser.RemoveMorphology(tree)
+	}
+	if q := query["terms"]; len(q) > 0 && q[0] == "true" {
+		parser.AddElidedTerminators(tree)
+	}
+	parser.RemoveSpace(tree)
+	parser.CollapseLists(tree)
+}
+
 func prettyString(printer func(io.Writer, *peg.Node) error, tree *peg.Node) string {
 	buf := bytes.NewBuffer(nil)
 	printer(buf, tree)
 	return buf.String()
 }
 
+func prettyFail(tree *peg.Fail) string {
+	buf := bytes.NewBuffer(nil)
+	peg.PrettyWrite(buf, tree)
+	return buf.String()
+}
+
 // parserDialect looks up the parser.Dialect for the requested parser.
 // If the dialect is not supported, a user-readable error is returned.
 func parserDialect(url *url.URL) (*parser.Dialect, error) {
@@ -101,13 +322,5 @@ func parserDialect(url *url.URL) (*parser.Dialect, error) {
 			return &d, nil
 		}
 	}
-	return nil, errors.New(parserName + " is not supported. Supported dialects are: " + strings.Join(dialectNames, ", "))
+	return nil, errors.New(parserName + " is not supported. Supported dialects are: " + strings.Join(parser.DialectNames(), ", "))
 }
-
-var dialectNames = func() []string {
-	var ns []string
-	for _, d := range parser.Dialects() {
-		ns = append(ns, d.Name)
-	}
-	return ns
-}()